@@ -0,0 +1,219 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// testFileSuffixes are the endings of the names of test files.
+var testFileSuffixes = []string{"_test.toml", "_test.yaml", "_test.yml", "_test.json"}
+
+// testFileExts are the extensions of files that can be given as
+// arguments, whatever their names.
+var testFileExts = map[string]bool{".toml": true, ".yaml": true, ".yml": true, ".json": true}
+
+// finder discovers the test files to load. A nil finder searches
+// the whole configuration folder.
+type finder struct {
+	// paths are the files and folders given as arguments, which
+	// are searched instead of the configuration folder.
+	paths []string
+
+	// include and ignore filter the files found in folders by
+	// their path relative to the configuration folder.
+	include []*regexp.Regexp
+	ignore  []*regexp.Regexp
+}
+
+// newFinder builds a finder from the command line arguments and
+// flags.
+func newFinder(paths, include, ignore []string) *finder {
+	return &finder{
+		paths:   paths,
+		include: compilePathPatterns(include),
+		ignore:  compilePathPatterns(ignore),
+	}
+}
+
+// find returns the test files under the finder's paths, or the
+// configuration folder, in natural order. Folders are searched
+// recursively, skipping the fragments folder and those whose names
+// start with a dot.
+func (f *finder) find(config string) ([]string, error) {
+	if f == nil {
+		f = &finder{}
+	}
+	paths := f.paths
+	if len(paths) == 0 {
+		paths = []string{config}
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Files named as arguments are loaded whatever their
+		// names, as long as they're in a format litmus reads.
+		if !info.IsDir() {
+			if !testFileExts[strings.ToLower(filepath.Ext(path))] {
+				return nil, errors.Errorf("%s is not a TOML, YAML or JSON file", path)
+			}
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if file != path && strings.HasPrefix(info.Name(), ".") || domain.SamePath(file, filepath.Join(config, fragmentsDir)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isTestFile(file) || seen[file] || !f.matches(config, file) {
+				return nil
+			}
+			seen[file] = true
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "searching %s", path)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return naturalLess(filepath.ToSlash(files[i]), filepath.ToSlash(files[j]))
+	})
+	return files, nil
+}
+
+// matches reports whether a file found in a folder passes the
+// include and ignore patterns.
+func (f *finder) matches(root, file string) bool {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		rel = file
+	}
+	rel = filepath.ToSlash(rel)
+
+	if len(f.include) > 0 && !matchAny(f.include, rel) {
+		return false
+	}
+	return !matchAny(f.ignore, rel)
+}
+
+func isTestFile(file string) bool {
+	name := strings.ToLower(filepath.Base(file))
+	for _, suffix := range testFileSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePathPatterns compiles globs matched against paths relative
+// to the configuration folder. * and ? match within a folder or
+// file name, and ** across folders. A pattern matching a folder
+// matches everything in it, and a pattern without a slash matches
+// a name at any depth, so "fixtures" matches every folder or file
+// named fixtures.
+func compilePathPatterns(patterns []string) (compiled []*regexp.Regexp) {
+	for _, p := range patterns {
+		compiled = append(compiled, regexp.MustCompile(pathGlobToRegexp(p)))
+	}
+	return
+}
+
+func pathGlobToRegexp(glob string) string {
+	glob = strings.Trim(filepath.ToSlash(glob), "/")
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		b.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("(/.*)?$")
+	return b.String()
+}
+
+// naturalLess orders paths by comparing their folder and file names
+// in turn, with runs of digits compared by value, so 2_test.toml
+// comes before 10_test.toml.
+func naturalLess(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] != bs[i] {
+			return naturalLessName(as[i], bs[i])
+		}
+	}
+	return len(as) < len(bs)
+}
+
+func naturalLessName(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+		if da != "" && db != "" {
+			// Compare by value, ignoring leading zeros, then
+			// by length so that 01 and 1 have an order.
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			if len(da) != len(db) {
+				return len(da) < len(db)
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
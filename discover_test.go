@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestNaturalLess(t *testing.T) {
+	paths := []string{
+		"10_test.toml",
+		"orders/2_test.toml",
+		"2_test.toml",
+		"orders/10_test.toml",
+		"orders/1/a_test.toml",
+		"02_test.toml",
+		"b_test.toml",
+		"a_test.yaml",
+		"a_test.toml",
+	}
+	sort.Slice(paths, func(i, j int) bool { return naturalLess(paths[i], paths[j]) })
+	test.Equals(t, []string{
+		"2_test.toml",
+		"02_test.toml",
+		"10_test.toml",
+		"a_test.toml",
+		"a_test.yaml",
+		"b_test.toml",
+		"orders/1/a_test.toml",
+		"orders/2_test.toml",
+		"orders/10_test.toml",
+	}, paths)
+}
+
+func TestPathPatterns(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		exp     bool
+	}{
+		{pattern: "fixtures", path: "fixtures/a_test.toml", exp: true},
+		{pattern: "fixtures", path: "orders/fixtures/a_test.toml", exp: true},
+		{pattern: "fixtures", path: "orders/fixtures_test.toml", exp: false},
+		{pattern: "*_smoke_test.toml", path: "orders/get_smoke_test.toml", exp: true},
+		{pattern: "orders/*", path: "orders/a_test.toml", exp: true},
+		{pattern: "orders/*", path: "users/orders/a_test.toml", exp: false},
+		{pattern: "orders/**", path: "orders/v1/a_test.toml", exp: true},
+		{pattern: "**/v1", path: "orders/v1/a_test.toml", exp: true},
+		{pattern: "**/v1", path: "v1/a_test.toml", exp: true},
+		{pattern: "v?", path: "orders/v1/a_test.toml", exp: true},
+		{pattern: "orders/", path: "orders/a_test.toml", exp: true},
+		{pattern: "a.test.toml", path: "a_test.toml", exp: false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern+" "+c.path, func(t *testing.T) {
+			test.Equals(t, c.exp, matchAny(compilePathPatterns([]string{c.pattern}), c.path))
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"10_test.toml":                "",
+		"2_test.yml":                  "",
+		"env.toml":                    "",
+		"suite.toml":                  "",
+		"users.csv":                   "",
+		"orders/1_test.json":          "",
+		"orders/fixtures/a_test.toml": "",
+		"orders/notes.toml":           "",
+		".git/a_test.toml":            "",
+	})
+	defer os.RemoveAll(dir)
+
+	rel := func(files []string) (paths []string) {
+		for _, f := range files {
+			r, err := filepath.Rel(dir, f)
+			test.ErrorNil(t, err)
+			paths = append(paths, filepath.ToSlash(r))
+		}
+		return
+	}
+
+	cases := []struct {
+		name    string
+		finder  *finder
+		exp     []string
+		wantErr bool
+	}{
+		{
+			name:   "whole folder",
+			finder: nil,
+			exp:    []string{"2_test.yml", "10_test.toml", "orders/1_test.json", "orders/fixtures/a_test.toml"},
+		},
+		{
+			name:   "ignore",
+			finder: newFinder(nil, nil, []string{"fixtures"}),
+			exp:    []string{"2_test.yml", "10_test.toml", "orders/1_test.json"},
+		},
+		{
+			name:   "include",
+			finder: newFinder(nil, []string{"orders/**"}, []string{"fixtures"}),
+			exp:    []string{"orders/1_test.json"},
+		},
+		{
+			name:   "paths",
+			finder: newFinder([]string{filepath.Join(dir, "orders"), filepath.Join(dir, "orders/notes.toml"), filepath.Join(dir, "10_test.toml")}, nil, nil),
+			exp:    []string{"10_test.toml", "orders/1_test.json", "orders/fixtures/a_test.toml", "orders/notes.toml"},
+		},
+		{
+			name:    "missing path",
+			finder:  newFinder([]string{filepath.Join(dir, "missing_test.toml")}, nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported file",
+			finder:  newFinder([]string{filepath.Join(dir, "users.csv")}, nil, nil),
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			files, err := c.finder.find(dir)
+			if c.wantErr {
+				test.Assert(t, err != nil)
+				return
+			}
+			test.ErrorNil(t, err)
+			test.Equals(t, c.exp, rel(files))
+		})
+	}
+}
+
+func TestLoadRequestsFormats(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="toml"
+url="http://x"`,
+		"b_test.yml": `
+litmus:
+  test:
+  - name: yml
+    url: http://x`,
+		"nested/c_test.json": `{
+  "litmus": {
+    "test": [{
+      "name": "json",
+      "url": "http://x",
+      "wants_code": 201,
+      "body": {"zulu": "1", "alpha": "2"}
+    }]
+  }
+}`,
+	})
+	defer os.RemoveAll(dir)
+
+	files, err := loadRequests(dir, nil, true)
+	test.ErrorNil(t, err)
+	test.Equals(t, 3, len(files))
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Litmus.Test[0].Name)
+	}
+	test.Equals(t, []string{"toml", "yml", "json"}, names)
+
+	json := files[2].Litmus.Test[0]
+	test.Equals(t, 201, json.WantsCode)
+	test.Equals(t, map[string]interface{}{"zulu": "1", "alpha": "2"}, json.Body)
+}
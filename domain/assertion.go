@@ -0,0 +1,487 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Assertion operators that can be used in the op field of
+// a head or body entry.
+const (
+	OpEquals     = "eq"
+	OpNotEquals  = "ne"
+	OpContains   = "contains"
+	OpStartsWith = "starts_with"
+	OpEndsWith   = "ends_with"
+	OpMatches    = "matches"
+	OpGreater    = "gt"
+	OpGreaterEq  = "gte"
+	OpLess       = "lt"
+	OpLessEq     = "lte"
+	OpBetween    = "between"
+	OpExists     = "exists"
+	OpAbsent     = "absent"
+	OpNull       = "null"
+	OpType       = "type"
+	OpLength     = "length"
+	OpOneOf      = "one_of"
+)
+
+var operators = map[string]bool{
+	OpEquals: true, OpNotEquals: true, OpContains: true, OpStartsWith: true,
+	OpEndsWith: true, OpMatches: true, OpGreater: true, OpGreaterEq: true,
+	OpLess: true, OpLessEq: true, OpBetween: true, OpExists: true,
+	OpAbsent: true, OpNull: true, OpType: true, OpLength: true, OpOneOf: true,
+}
+
+// Assertion is a single check performed against a value
+// taken from a response.
+type Assertion struct {
+	// Path locates the value in the response.
+	Path string
+	// Op is the operator used to compare the value.
+	Op string
+	// Value is the operand, where the operator requires one.
+	Value interface{}
+	// Set is the environment key the value is captured
+	// into when the assertion passes.
+	Set string
+}
+
+// extractAssertion builds an Assertion from a head or body entry.
+// Entries take one of the following forms:
+//
+//	path = "expected"
+//	set = {path = "expected"}
+//	label = {path = "items.#", op = "gte", value = 3, set = "count"}
+//
+// In the last form, path defaults to the entry's key.
+func extractAssertion(key string, value interface{}) (a Assertion, err error) {
+	if m, ok := value.(map[interface{}]interface{}); ok {
+		value = convertInterfaceMap(m)
+	}
+
+	if m, ok := value.(map[string]interface{}); ok && isOperatorMap(m) {
+		return operatorAssertion(key, m)
+	}
+
+	path, expected, set, err := extractParam(key, value)
+	if err != nil {
+		return
+	}
+	return Assertion{Path: path, Op: OpEquals, Value: expected, Set: set}, nil
+}
+
+func isOperatorMap(m map[string]interface{}) bool {
+	_, op := m["op"]
+	_, path := m["path"]
+	_, value := m["value"]
+	return op || (path && value)
+}
+
+func operatorAssertion(key string, m map[string]interface{}) (a Assertion, err error) {
+	a = Assertion{Path: key, Op: OpEquals}
+	for k, v := range m {
+		switch k {
+		case "path":
+			if a.Path, err = stringField(k, v); err != nil {
+				return
+			}
+		case "op":
+			if a.Op, err = stringField(k, v); err != nil {
+				return
+			}
+		case "set":
+			if a.Set, err = stringField(k, v); err != nil {
+				return
+			}
+		case "value":
+			a.Value = v
+		default:
+			return a, errors.Errorf("unknown assertion field %q", k)
+		}
+	}
+
+	a.Op = strings.ToLower(a.Op)
+	return a, a.validate()
+}
+
+// validate checks the operator is known and has a suitable value.
+func (a *Assertion) validate() error {
+	if !operators[a.Op] {
+		return errors.Errorf("unknown assertion operator %q", a.Op)
+	}
+
+	switch a.Op {
+	case OpBetween:
+		if l, ok := a.Value.([]interface{}); !ok || len(l) != 2 {
+			return errors.Errorf("%s requires a value of [min, max]", a.Op)
+		}
+	case OpOneOf:
+		if _, ok := a.Value.([]interface{}); !ok {
+			return errors.Errorf("%s requires a list value", a.Op)
+		}
+	case OpExists, OpAbsent, OpNull:
+	default:
+		if a.Value == nil {
+			return errors.Errorf("%s requires a value", a.Op)
+		}
+	}
+	return nil
+}
+
+func stringField(name string, v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.Errorf("expected string for %q but got: %T", name, v)
+	}
+	return s, nil
+}
+
+// stringOperands converts the assertion's operands to strings,
+// for use against sources that only return strings, such as
+// headers, so that an expectation of 3 matches a value of "3".
+func (a *Assertion) stringOperands() {
+	switch x := a.Value.(type) {
+	case nil:
+	case []interface{}:
+		if a.Op == OpOneOf {
+			l := make([]interface{}, len(x))
+			for i, v := range x {
+				l[i] = stringify(v)
+			}
+			a.Value = l
+		}
+	default:
+		if a.Op == OpEquals || a.Op == OpNotEquals {
+			a.Value = stringify(x)
+		}
+	}
+}
+
+// expectation describes what the assertion expects, for
+// use in failure messages.
+func (a *Assertion) expectation() string {
+	switch a.Op {
+	case OpEquals:
+		return stringify(a.Value)
+	case OpNotEquals:
+		return "not " + stringify(a.Value)
+	case OpContains:
+		return "contains " + stringify(a.Value)
+	case OpStartsWith:
+		return "starts with " + stringify(a.Value)
+	case OpEndsWith:
+		return "ends with " + stringify(a.Value)
+	case OpMatches:
+		return "matches /" + stringify(a.Value) + "/"
+	case OpGreater:
+		return "> " + stringify(a.Value)
+	case OpGreaterEq:
+		return ">= " + stringify(a.Value)
+	case OpLess:
+		return "< " + stringify(a.Value)
+	case OpLessEq:
+		return "<= " + stringify(a.Value)
+	case OpBetween:
+		l := a.Value.([]interface{})
+		return fmt.Sprintf("between %s and %s", stringify(l[0]), stringify(l[1]))
+	case OpExists:
+		return "to exist"
+	case OpAbsent:
+		return "to be absent"
+	case OpNull:
+		return "null"
+	case OpType:
+		return "type " + stringify(a.Value)
+	case OpLength:
+		return "length " + stringify(a.Value)
+	case OpOneOf:
+		return "one of " + stringify(a.Value)
+	}
+	return a.Op + " " + stringify(a.Value)
+}
+
+// evaluate checks the assertion against the actual value. The
+// found flag is false if nothing existed at the assertion's
+// path. A nil result means the assertion passed.
+func (a *Assertion) evaluate(source string, actual interface{}, found bool) *AssertionError {
+	fail := &AssertionError{
+		Source:   source,
+		Path:     a.Path,
+		Op:       a.Op,
+		Expected: a.expectation(),
+		Actual:   stringify(actual),
+	}
+
+	switch a.Op {
+	case OpExists:
+		if found {
+			return nil
+		}
+		fail.Actual = "absent"
+		return fail
+	case OpAbsent:
+		if !found {
+			return nil
+		}
+		return fail
+	}
+
+	if !found {
+		fail.Err = &NoValueError{Path: a.Path, Source: source}
+		return fail
+	}
+
+	ok, err := a.compare(actual)
+	if err != nil {
+		fail.Err = err
+		return fail
+	}
+	if !ok {
+		switch a.Op {
+		case OpEquals, OpNotEquals:
+			// Values that only differ by type would otherwise
+			// produce an indistinguishable message.
+			if fail.Expected == fail.Actual {
+				fail.Expected += " (" + typeOf(a.Value) + ")"
+				fail.Actual += " (" + typeOf(actual) + ")"
+			}
+		case OpType:
+			fail.Actual = "type " + typeOf(actual)
+		case OpLength:
+			if n, err := length(actual); err == nil {
+				fail.Actual = fmt.Sprintf("length %d", n)
+			}
+		}
+		return fail
+	}
+	return nil
+}
+
+func (a *Assertion) compare(actual interface{}) (bool, error) {
+	act := stringify(actual)
+	exp := stringify(a.Value)
+
+	switch a.Op {
+	case OpEquals:
+		return equal(a.Value, actual), nil
+	case OpNotEquals:
+		return !equal(a.Value, actual), nil
+	case OpContains:
+		if l, ok := actual.([]interface{}); ok {
+			for _, v := range l {
+				if equal(a.Value, v) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(act, exp), nil
+	case OpStartsWith:
+		return strings.HasPrefix(act, exp), nil
+	case OpEndsWith:
+		return strings.HasSuffix(act, exp), nil
+	case OpMatches:
+		re, err := regexp.Compile(exp)
+		if err != nil {
+			return false, errors.Wrapf(err, "compiling expression %q", exp)
+		}
+		return re.MatchString(act), nil
+	case OpGreater, OpGreaterEq, OpLess, OpLessEq:
+		return compareNumbers(a.Op, actual, a.Value)
+	case OpBetween:
+		l := a.Value.([]interface{})
+		lower, err := compareNumbers(OpGreaterEq, actual, l[0])
+		if err != nil {
+			return false, err
+		}
+		upper, err := compareNumbers(OpLessEq, actual, l[1])
+		if err != nil {
+			return false, err
+		}
+		return lower && upper, nil
+	case OpNull:
+		return actual == nil, nil
+	case OpType:
+		return typeOf(actual) == strings.ToLower(exp), nil
+	case OpLength:
+		n, err := length(actual)
+		if err != nil {
+			return false, err
+		}
+		want, err := toFloat(a.Value)
+		if err != nil {
+			return false, errors.Wrap(err, "parsing expected length")
+		}
+		return float64(n) == want, nil
+	case OpOneOf:
+		for _, v := range a.Value.([]interface{}) {
+			if equal(v, actual) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, errors.Errorf("unknown assertion operator %q", a.Op)
+}
+
+// equal compares two values, taking their type into account.
+// Numbers are compared numerically, so 1 equals 1.0, but 1 does
+// not equal "1", true does not equal "true" and null does not
+// equal "".
+func equal(exp, act interface{}) bool {
+	return reflect.DeepEqual(normalizeNumbers(exp), normalizeNumbers(act))
+}
+
+// normalizeNumbers converts the numbers in a value, including
+// those nested in arrays and objects, to a single representation:
+// int64 for whole numbers and float64 otherwise. YAML maps are
+// converted to string keyed maps along the way.
+func normalizeNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint64:
+		if x <= math.MaxInt64 {
+			return int64(x)
+		}
+		return float64(x)
+	case float32:
+		return normalizeNumbers(float64(x))
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x)
+		}
+		return x
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			out[i] = normalizeNumbers(item)
+		}
+		return out
+	case map[interface{}]interface{}:
+		return normalizeNumbers(convertInterfaceMap(x))
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			out[k] = normalizeNumbers(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func compareNumbers(op string, actual, expected interface{}) (bool, error) {
+	act, err := toFloat(actual)
+	if err != nil {
+		return false, errors.Wrap(err, "parsing actual value")
+	}
+	exp, err := toFloat(expected)
+	if err != nil {
+		return false, errors.Wrap(err, "parsing expected value")
+	}
+
+	switch op {
+	case OpGreater:
+		return act > exp, nil
+	case OpGreaterEq:
+		return act >= exp, nil
+	case OpLess:
+		return act < exp, nil
+	default:
+		return act <= exp, nil
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		if err != nil {
+			return 0, errors.Errorf("%q is not a number", x)
+		}
+		return f, nil
+	default:
+		return 0, errors.Errorf("%v (%T) is not a number", v, v)
+	}
+}
+
+func length(v interface{}) (int, error) {
+	switch x := v.(type) {
+	case string:
+		return utf8.RuneCountInString(x), nil
+	case []interface{}:
+		return len(x), nil
+	case map[string]interface{}:
+		return len(x), nil
+	default:
+		return 0, errors.Errorf("%s has no length", typeOf(v))
+	}
+}
+
+// typeOf returns the JSON type name of a value.
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, float32, int, int32, int64, uint64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// stringify returns the string form of a value, encoding
+// arrays and objects as JSON.
+func stringify(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case map[interface{}]interface{}:
+		return stringify(convertInterfaceMap(x))
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(x)
+		if err != nil {
+			return fmt.Sprintf("%v", x)
+		}
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", x)
+	}
+}
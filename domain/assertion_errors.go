@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Sources of an assertion, identifying which part of the
+// response was being checked.
+const (
+	SourceStatus = "status"
+	SourceHeader = "header"
+	SourceBody   = "body"
+	SourceCookie = "cookie"
+	SourceGetter = "getter"
+)
+
+// AssertionError describes a single failed assertion.
+type AssertionError struct {
+	// Source is the part of the response being asserted.
+	Source string
+	// Path is the header name or body path being asserted.
+	Path string
+	// Op is the assertion operator that was applied.
+	Op string
+	// Expected is the value the test expected to find.
+	Expected string
+	// Actual is the value found in the response.
+	Actual string
+	// Err is set when the assertion could not be evaluated,
+	// for example because the path didn't exist.
+	Err error
+}
+
+// Error returns the string representation of an AssertionError.
+func (e *AssertionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %q: %v", e.Source, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s %q:\n\t\texp: %v\n\t\tgot: %v", e.Source, e.Path, e.Expected, e.Actual)
+}
+
+// NoValueError is returned by getters when nothing exists
+// at the requested path.
+type NoValueError struct {
+	Path   string
+	Source string
+}
+
+// Error returns the string representation of a NoValueError.
+func (e *NoValueError) Error() string {
+	return fmt.Sprintf("no value at path %q in %s", e.Path, e.Source)
+}
+
+// IsNoValue returns true if err, or its cause, is a NoValueError.
+func IsNoValue(err error) bool {
+	_, ok := errors.Cause(err).(*NoValueError)
+	return ok
+}
+
+// AssertionErrors is a collection of failed assertions.
+type AssertionErrors []*AssertionError
+
+// Error returns the string representation of AssertionErrors.
+func (e AssertionErrors) Error() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%d assertion(s) failed:", len(e))
+	for _, err := range e {
+		fmt.Fprintf(buf, "\n\t- %v", err)
+	}
+	return buf.String()
+}
+
+// ErrOrNil returns nil if there are no errors in the
+// collection, avoiding the non-nil interface trap of
+// returning an empty slice as an error.
+func (e AssertionErrors) ErrOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Append adds the assertion errors contained in err to
+// the collection. Errors that aren't assertion errors are
+// recorded against the given source.
+func (e AssertionErrors) Append(source string, err error) AssertionErrors {
+	switch x := err.(type) {
+	case nil:
+		return e
+	case AssertionErrors:
+		return append(e, x...)
+	case *AssertionError:
+		return append(e, x)
+	default:
+		return append(e, &AssertionError{Source: source, Err: err})
+	}
+}
@@ -0,0 +1,273 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/LUSHDigital/litmus/test"
+	"github.com/h2non/gock"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExtractAssertion(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   interface{}
+		exp     Assertion
+		wantErr bool
+	}{
+		{
+			name:  "plain",
+			key:   "hello",
+			value: "world",
+			exp:   Assertion{Path: "hello", Op: OpEquals, Value: "world"},
+		},
+		{
+			name:  "setter",
+			key:   "greeting",
+			value: map[string]interface{}{"hello": "world"},
+			exp:   Assertion{Path: "hello", Op: OpEquals, Value: "world", Set: "greeting"},
+		},
+		{
+			name:  "operator",
+			key:   "count",
+			value: map[string]interface{}{"path": "items.#", "op": "GTE", "value": int64(3), "set": "n"},
+			exp:   Assertion{Path: "items.#", Op: OpGreaterEq, Value: int64(3), Set: "n"},
+		},
+		{
+			name:  "operator path defaults to key",
+			key:   "items",
+			value: map[interface{}]interface{}{"op": "exists"},
+			exp:   Assertion{Path: "items", Op: OpExists},
+		},
+		{
+			name:    "unknown operator",
+			key:     "items",
+			value:   map[string]interface{}{"op": "approx", "value": 1},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			key:     "items",
+			value:   map[string]interface{}{"op": "eq", "value": 1, "extra": 2},
+			wantErr: true,
+		},
+		{
+			name:    "between without range",
+			key:     "items",
+			value:   map[string]interface{}{"op": "between", "value": 1},
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			key:     "items",
+			value:   map[string]interface{}{"op": "contains"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := extractAssertion(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr {
+				test.Equals(t, tt.exp, act)
+			}
+		})
+	}
+}
+
+func TestAssertionEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      Assertion
+		actual interface{}
+		found  bool
+		pass   bool
+	}{
+		{"eq", Assertion{Op: OpEquals, Value: "a"}, "a", true, true},
+		{"eq fail", Assertion{Op: OpEquals, Value: "a"}, "b", true, false},
+		{"ne", Assertion{Op: OpNotEquals, Value: "a"}, "b", true, true},
+		{"contains", Assertion{Op: OpContains, Value: "json"}, "application/json", true, true},
+		{"starts_with", Assertion{Op: OpStartsWith, Value: "app"}, "application/json", true, true},
+		{"ends_with fail", Assertion{Op: OpEndsWith, Value: "xml"}, "application/json", true, false},
+		{"matches", Assertion{Op: OpMatches, Value: `^\d{3}$`}, "123", true, true},
+		{"gt", Assertion{Op: OpGreater, Value: int64(2)}, "3", true, true},
+		{"gte equal", Assertion{Op: OpGreaterEq, Value: 3.0}, 3.0, true, true},
+		{"lt fail", Assertion{Op: OpLess, Value: int64(2)}, "3", true, false},
+		{"lte not a number", Assertion{Op: OpLessEq, Value: int64(2)}, "abc", true, false},
+		{"between", Assertion{Op: OpBetween, Value: []interface{}{int64(1), int64(10)}}, "5", true, true},
+		{"between fail", Assertion{Op: OpBetween, Value: []interface{}{int64(1), int64(10)}}, "11", true, false},
+		{"exists", Assertion{Op: OpExists}, "x", true, true},
+		{"exists fail", Assertion{Op: OpExists}, nil, false, false},
+		{"absent", Assertion{Op: OpAbsent}, nil, false, true},
+		{"absent fail", Assertion{Op: OpAbsent}, "x", true, false},
+		{"null", Assertion{Op: OpNull}, nil, true, true},
+		{"null fail", Assertion{Op: OpNull}, "", true, false},
+		{"type number", Assertion{Op: OpType, Value: "number"}, 1.5, true, true},
+		{"type array", Assertion{Op: OpType, Value: "array"}, []interface{}{}, true, true},
+		{"type object fail", Assertion{Op: OpType, Value: "object"}, "{}", true, false},
+		{"length string", Assertion{Op: OpLength, Value: int64(3)}, "abc", true, true},
+		{"length array", Assertion{Op: OpLength, Value: 2}, []interface{}{1, 2}, true, true},
+		{"one_of", Assertion{Op: OpOneOf, Value: []interface{}{"a", "b"}}, "b", true, true},
+		{"one_of fail", Assertion{Op: OpOneOf, Value: []interface{}{"a", "b"}}, "c", true, false},
+		{"missing value", Assertion{Op: OpEquals, Value: "a"}, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.a.evaluate(SourceBody, tt.actual, tt.found)
+			if (err == nil) != tt.pass {
+				t.Fatalf("evaluate() error = %v, pass %v", err, tt.pass)
+			}
+		})
+	}
+}
+
+var tomlOperators = `
+[litmus]
+[[litmus.test]]
+[litmus.test.body]
+"items.#" = {op="gte", value=2}
+"items.0.name" = {op="one_of", value=["apple", "pear"], set="first"}
+"items.1.price" = {op="between", value=[1, 10]}
+"total" = {op="type", value="number"}
+"discount" = {op="null"}
+"missing" = {op="absent"}`
+
+var yamlOperators = `
+litmus:
+  test:
+  - body:
+      items.#: {op: gte, value: 2}
+      items.0.name: {op: one_of, value: [apple, pear], set: first}
+      items.1.price: {op: between, value: [1, 10]}
+      total: {op: type, value: number}
+      discount: {op: "null"}
+      missing: {op: absent}`
+
+func TestBodyOperators(t *testing.T) {
+	const body = `{"items":[{"name":"apple","price":1},{"name":"pear","price":2.5}],"total":3.5,"discount":null}`
+
+	var tomlFile, yamlFile TestFile
+	if err := toml.Unmarshal([]byte(tomlOperators), &tomlFile); err != nil {
+		t.Fatalf("error unmarshalling test file: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(yamlOperators), &yamlFile); err != nil {
+		t.Fatalf("error unmarshalling test file: %v", err)
+	}
+
+	for name, tf := range map[string]TestFile{"toml": tomlFile, "yaml": yamlFile} {
+		t.Run(name, func(t *testing.T) {
+			defer gock.Off()
+			gock.New("/").
+				Reply(200).
+				SetHeader("content-type", "application/json").
+				BodyString(body)
+
+			res, err := http.Get("/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			env := map[string]interface{}{}
+			r := tf.Litmus.Test[0]
+			if err := r.ApplyEnv(env); err != nil {
+				t.Fatal(err)
+			}
+
+			test.ErrorNil(t, Body(&r, res, env))
+			test.Equals(t, "apple", env["first"])
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		exp  interface{}
+		act  interface{}
+		want bool
+	}{
+		{"int and float", int64(1), 1.0, true},
+		{"yaml int and float", 1, 1.0, true},
+		{"fractions", 1.5, 1.5, true},
+		{"number and string", int64(1), "1", false},
+		{"bool and string", true, "true", false},
+		{"null and empty string", nil, "", false},
+		{"null", nil, nil, true},
+		{"arrays", []interface{}{int64(1), "a"}, []interface{}{1.0, "a"}, true},
+		{"objects", map[interface{}]interface{}{"a": 1}, map[string]interface{}{"a": 1.0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.Equals(t, tt.want, equal(tt.exp, tt.act))
+		})
+	}
+}
+
+var tomlTyped = `
+[litmus]
+[[litmus.test]]
+[litmus.test.body]
+id = 42
+price = 1.5
+active = true
+"tags.0" = "new"
+id_key = {id = 42}`
+
+func TestBodyTyped(t *testing.T) {
+	defer gock.Off()
+	gock.New("/").
+		Reply(200).
+		SetHeader("content-type", "application/json").
+		BodyString(`{"id":42.0,"price":1.5,"active":true,"tags":["new"]}`)
+
+	res, err := http.Get("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tf TestFile
+	if err := toml.Unmarshal([]byte(tomlTyped), &tf); err != nil {
+		t.Fatalf("error unmarshalling test file: %v", err)
+	}
+
+	env := map[string]interface{}{}
+	r := tf.Litmus.Test[0]
+	if err := r.ApplyEnv(env); err != nil {
+		t.Fatal(err)
+	}
+
+	test.ErrorNil(t, Body(&r, res, env))
+	test.Equals(t, int64(42), env["id_key"])
+}
+
+func TestBodyTypeMismatch(t *testing.T) {
+	defer gock.Off()
+	gock.New("/").
+		Reply(200).
+		SetHeader("content-type", "application/json").
+		BodyString(`{"active":true,"deleted":null}`)
+
+	res, err := http.Get("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &RequestTest{
+		Body: map[string]interface{}{
+			"active":  "true",
+			"deleted": "",
+		},
+	}
+
+	err = Body(r, res, map[string]interface{}{})
+	errs, ok := err.(AssertionErrors)
+	if !ok {
+		t.Fatalf("expected AssertionErrors but got: %T", err)
+	}
+	test.Equals(t, 2, len(errs))
+}
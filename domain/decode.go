@@ -0,0 +1,164 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// UnmarshalTOML decodes a TOML test file, recording the order in
+// which the head and body entries of each test were declared.
+func UnmarshalTOML(data []byte, tf *TestFile) error {
+	md, err := toml.Decode(string(data), tf)
+	if err != nil {
+		return errors.Wrap(err, "unmarshalling")
+	}
+
+	recordTOMLOrder(md, tf)
+	return nil
+}
+
+// recordTOMLOrder records the order of the head and body entries
+// of each request from the decoder's metadata.
+func recordTOMLOrder(md toml.MetaData, tf *TestFile) {
+	index := make(map[string]int)
+	for _, key := range md.Keys() {
+		if len(key) < 2 || key[0] != "litmus" {
+			continue
+		}
+		section := key[1]
+		if section == sectionTemplates {
+			if len(key) >= 5 {
+				t := tf.Litmus.Templates[key[2]]
+				t.recordOrder(key[3], key[4])
+				tf.Litmus.Templates[key[2]] = t
+			}
+			continue
+		}
+		if _, ok := index[section]; !ok {
+			index[section] = -1
+		}
+		if len(key) == 2 {
+			index[section]++
+			continue
+		}
+
+		requests := tf.Litmus.Section(section)
+		i := index[section]
+		if len(key) < 4 || i < 0 || i >= len(requests) {
+			continue
+		}
+
+		requests[i].recordOrder(key[2], key[3])
+	}
+}
+
+// recordOrder records that key was declared next in the request's
+// head or body.
+func (r *RequestTest) recordOrder(field, key string) {
+	switch field {
+	case "body":
+		r.bodyOrder = appendUnique(r.bodyOrder, key)
+	case "head":
+		r.headOrder = appendUnique(r.headOrder, key)
+	}
+}
+
+// UnmarshalYAML decodes a YAML test file, recording the order in
+// which the head and body entries of each test were declared.
+func UnmarshalYAML(data []byte, tf *TestFile) error {
+	if err := yaml.Unmarshal(data, tf); err != nil {
+		return errors.Wrap(err, "unmarshalling")
+	}
+
+	// Decode a second time, into ordered maps, to find the
+	// order of the keys.
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return errors.Wrap(err, "unmarshalling")
+	}
+
+	litmus, _ := lookupMapSlice(doc, "litmus").(yaml.MapSlice)
+	for _, section := range []string{SectionTest, SectionSetup, SectionTeardown, SectionRequest} {
+		requests := tf.Litmus.Section(section)
+		items, _ := lookupMapSlice(litmus, section).([]interface{})
+		for i, item := range items {
+			test, ok := item.(yaml.MapSlice)
+			if !ok || i >= len(requests) {
+				continue
+			}
+
+			requests[i].recordYAMLOrder(test)
+		}
+	}
+
+	templates, _ := lookupMapSlice(litmus, sectionTemplates).(yaml.MapSlice)
+	for _, item := range templates {
+		name := fmt.Sprintf("%v", item.Key)
+		test, ok := item.Value.(yaml.MapSlice)
+		t, defined := tf.Litmus.Templates[name]
+		if !ok || !defined {
+			continue
+		}
+		t.recordYAMLOrder(test)
+		tf.Litmus.Templates[name] = t
+	}
+	return nil
+}
+
+func (r *RequestTest) recordYAMLOrder(test yaml.MapSlice) {
+	r.bodyOrder = mapSliceKeys(lookupMapSlice(test, "body"))
+	r.headOrder = mapSliceKeys(lookupMapSlice(test, "head"))
+}
+
+func lookupMapSlice(ms yaml.MapSlice, key string) interface{} {
+	for _, item := range ms {
+		if fmt.Sprintf("%v", item.Key) == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func mapSliceKeys(v interface{}) (keys []string) {
+	ms, _ := v.(yaml.MapSlice)
+	for _, item := range ms {
+		keys = append(keys, fmt.Sprintf("%v", item.Key))
+	}
+	return
+}
+
+func appendUnique(keys []string, key string) []string {
+	for _, k := range keys {
+		if k == key {
+			return keys
+		}
+	}
+	return append(keys, key)
+}
+
+// orderedKeys returns the keys of m in the given order. Keys that
+// aren't in the order, such as those of tests built in code, are
+// appended in sorted order so iteration is always deterministic.
+func orderedKeys(m map[string]interface{}, order []string) []string {
+	keys := make([]string, 0, len(m))
+	seen := make(map[string]bool, len(m))
+	for _, k := range order {
+		if _, ok := m[k]; ok && !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+	}
+
+	var rest []string
+	for k := range m {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+	"github.com/h2non/gock"
+)
+
+var tomlOrdered = `
+[litmus]
+[[litmus.test]]
+name="first"
+[litmus.test.head]
+"X-Zebra" = "z"
+"X-Apple" = "a"
+[[litmus.test]]
+name="second"
+[litmus.test.body]
+token_key = {token = "abc"}
+zulu = "1"
+"echo" = "{{.token_key}}"
+alpha = {op="exists"}`
+
+var yamlOrdered = `
+litmus:
+  test:
+  - name: first
+    head:
+      X-Zebra: z
+      X-Apple: a
+  - name: second
+    body:
+      token_key: {token: abc}
+      zulu: "1"
+      echo: "{{.token_key}}"
+      alpha: {op: exists}`
+
+func TestUnmarshalOrder(t *testing.T) {
+	var tomlFile, yamlFile TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(tomlOrdered), &tomlFile))
+	test.ErrorNil(t, UnmarshalYAML([]byte(yamlOrdered), &yamlFile))
+
+	for name, tf := range map[string]TestFile{"toml": tomlFile, "yaml": yamlFile} {
+		t.Run(name, func(t *testing.T) {
+			first, second := tf.Litmus.Test[0], tf.Litmus.Test[1]
+			test.Equals(t, []string{"X-Zebra", "X-Apple"}, orderedKeys(first.Head, first.headOrder))
+			test.Equals(t, []string{"token_key", "zulu", "echo", "alpha"}, orderedKeys(second.Body, second.bodyOrder))
+		})
+	}
+}
+
+func TestOrderedKeysFallback(t *testing.T) {
+	m := map[string]interface{}{"c": 1, "a": 1, "b": 1}
+	test.Equals(t, []string{"b", "a", "c"}, orderedKeys(m, []string{"b"}))
+}
+
+func TestBodyUsesEarlierCaptures(t *testing.T) {
+	var tf TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(tomlOrdered), &tf))
+
+	defer gock.Off()
+	gock.New("/").
+		Reply(200).
+		SetHeader("content-type", "application/json").
+		BodyString(`{"token":"abc","zulu":"1","echo":"abc","alpha":true}`)
+
+	res, err := http.Get("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]interface{}{}
+	r := tf.Litmus.Test[1]
+	test.ErrorNil(t, r.ApplyEnv(env))
+	test.ErrorNil(t, Body(&r, res, env))
+	test.Equals(t, "abc", env["token_key"])
+}
+
+func TestUnmarshalSectionOrder(t *testing.T) {
+	tomlData := `
+[[litmus.setup]]
+name="create"
+[litmus.setup.body]
+zulu = "1"
+alpha = "2"
+[[litmus.test]]
+name="get"
+[litmus.test.body]
+yankee = "1"
+bravo = "2"`
+
+	yamlData := `
+litmus:
+  setup:
+  - name: create
+    body:
+      zulu: "1"
+      alpha: "2"
+  test:
+  - name: get
+    body:
+      yankee: "1"
+      bravo: "2"`
+
+	var tomlFile, yamlFile TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(tomlData), &tomlFile))
+	test.ErrorNil(t, UnmarshalYAML([]byte(yamlData), &yamlFile))
+
+	for name, tf := range map[string]TestFile{"toml": tomlFile, "yaml": yamlFile} {
+		t.Run(name, func(t *testing.T) {
+			setup, get := tf.Litmus.Setup[0], tf.Litmus.Test[0]
+			test.Equals(t, []string{"zulu", "alpha"}, orderedKeys(setup.Body, setup.bodyOrder))
+			test.Equals(t, []string{"yankee", "bravo"}, orderedKeys(get.Body, get.bodyOrder))
+		})
+	}
+}
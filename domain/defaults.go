@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// Defaults holds values for the requests in a file, or in the whole
+// suite, that don't set their own.
+type Defaults struct {
+	// BaseURL is joined with request URLs that don't have
+	// a scheme, such as "/users".
+	BaseURL   string            `toml:"base_url" yaml:"base_url"`
+	Method    string            `toml:"method" yaml:"method"`
+	Headers   map[string]string `toml:"headers" yaml:"headers"`
+	Query     map[string]string `toml:"query" yaml:"query"`
+	WantsCode int               `toml:"wants_code" yaml:"wants_code"`
+	Timeout   Duration          `toml:"timeout" yaml:"timeout"`
+	Auth      *Auth             `toml:"auth" yaml:"auth"`
+}
+
+// Auth describes the credentials sent with a request, as either a
+// bearer token or a username and password for basic auth.
+type Auth struct {
+	Bearer   string `toml:"bearer" yaml:"bearer"`
+	Username string `toml:"username" yaml:"username"`
+	Password string `toml:"password" yaml:"password"`
+}
+
+// Header returns the value of the Authorization header for the
+// credentials, or an empty string if there aren't any.
+func (a *Auth) Header() string {
+	switch {
+	case a == nil:
+		return ""
+	case a.Bearer != "":
+		return "Bearer " + a.Bearer
+	case a.Username != "" || a.Password != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+	return ""
+}
+
+// ApplyDefaults fills in the values that each request in the file
+// doesn't set from the file's defaults, then from parents, such as
+// the suite's defaults, in order.
+func (tf *TestFile) ApplyDefaults(parents ...*Defaults) {
+	defaults := append([]*Defaults{&tf.Litmus.Defaults}, parents...)
+	for _, section := range []string{SectionTest, SectionSetup, SectionTeardown, SectionRequest} {
+		requests := tf.Litmus.Section(section)
+		for i := range requests {
+			for _, d := range defaults {
+				d.apply(&requests[i])
+			}
+		}
+	}
+}
+
+func (d *Defaults) apply(r *RequestTest) {
+	if d == nil {
+		return
+	}
+
+	// A request's own Authorization header takes precedence over
+	// inherited credentials.
+	ownAuth := hasHeader(r.Headers, "Authorization")
+
+	if d.BaseURL != "" && isRelativeURL(r.URL) {
+		r.URL = joinURL(d.BaseURL, r.URL)
+	}
+	if r.Method == "" {
+		r.Method = d.Method
+	}
+	r.Headers = mergeMissing(r.Headers, d.Headers, true)
+	r.Query = mergeMissing(r.Query, d.Query, false)
+	if r.WantsCode == 0 {
+		r.WantsCode = d.WantsCode
+	}
+	if r.Timeout == 0 {
+		r.Timeout = d.Timeout
+	}
+	if r.Auth == nil && d.Auth != nil && !ownAuth {
+		auth := *d.Auth
+		r.Auth = &auth
+	}
+}
+
+// isRelativeURL reports whether url should be joined with a base
+// URL: it has no scheme and doesn't start with a template, which
+// may expand to one.
+func isRelativeURL(url string) bool {
+	return !strings.Contains(url, "://") && !strings.HasPrefix(url, "{{")
+}
+
+func joinURL(base, path string) string {
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// hasHeader reports whether headers has one named name, ignoring
+// case.
+func hasHeader(headers map[string]string, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeMissing returns m with the entries from defaults that it
+// doesn't have. Keys are compared ignoring case if ignoreCase is
+// set, as header names are, and exactly otherwise, as query
+// parameter names are.
+func mergeMissing(m, defaults map[string]string, ignoreCase bool) map[string]string {
+	if len(defaults) == 0 {
+		return m
+	}
+	key := func(k string) string {
+		if ignoreCase {
+			return strings.ToLower(k)
+		}
+		return k
+	}
+
+	out := make(map[string]string, len(m)+len(defaults))
+	have := make(map[string]bool, len(m))
+	for k, v := range m {
+		out[k] = v
+		have[key(k)] = true
+	}
+	for k, v := range defaults {
+		if !have[key(k)] {
+			out[k] = v
+		}
+	}
+	return out
+}
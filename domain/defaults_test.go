@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	data := `
+[litmus.defaults]
+base_url="http://{{.host}}/api/"
+method="GET"
+wants_code=200
+timeout="2s"
+[litmus.defaults.headers]
+Content-Type="application/json"
+[litmus.defaults.query]
+lang="en"
+page="1"
+[litmus.defaults.auth]
+bearer="{{.token}}"
+
+[[litmus.test]]
+name="relative"
+url="/users"
+
+[[litmus.test]]
+name="overridden"
+method="POST"
+url="http://other/users"
+wants_code=201
+timeout="5s"
+unset_headers=["Authorization"]
+[litmus.test.headers]
+content-type="text/plain"
+[litmus.test.query]
+lang="fr"
+Page="2"`
+
+	suite := &Defaults{
+		BaseURL: "http://ignored",
+		Headers: map[string]string{"X-Suite": "1", "Content-Type": "text/xml"},
+		Method:  "DELETE",
+	}
+
+	var tf TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(data), &tf))
+	tf.ApplyDefaults(suite)
+
+	relative, overridden := tf.Litmus.Test[0], tf.Litmus.Test[1]
+
+	test.Equals(t, "http://{{.host}}/api/users", relative.URL)
+	test.Equals(t, "GET", relative.Method)
+	test.Equals(t, 200, relative.WantsCode)
+	test.Equals(t, Duration(2*time.Second), relative.Timeout)
+	test.Equals(t, map[string]string{"Content-Type": "application/json", "X-Suite": "1"}, relative.Headers)
+	test.Equals(t, map[string]string{"lang": "en", "page": "1"}, relative.Query)
+	test.Equals(t, &Auth{Bearer: "{{.token}}"}, relative.Auth)
+
+	test.Equals(t, "http://other/users", overridden.URL)
+	test.Equals(t, "POST", overridden.Method)
+	test.Equals(t, 201, overridden.WantsCode)
+	test.Equals(t, Duration(5*time.Second), overridden.Timeout)
+	test.Equals(t, map[string]string{"content-type": "text/plain", "X-Suite": "1"}, overridden.Headers)
+	test.Equals(t, map[string]string{"lang": "fr", "Page": "2", "page": "1"}, overridden.Query)
+	test.Equals(t, []string{"Authorization"}, overridden.UnsetHeaders)
+}
+
+func TestApplyDefaultsAuthorizationHeader(t *testing.T) {
+	data := `
+[litmus.defaults.auth]
+bearer="default"
+
+[[litmus.test]]
+name="own header"
+url="http://localhost"
+[litmus.test.headers]
+authorization="Bearer own"
+
+[[litmus.test]]
+name="inherited"
+url="http://localhost"`
+
+	var tf TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(data), &tf))
+	tf.ApplyDefaults()
+
+	own, inherited := tf.Litmus.Test[0], tf.Litmus.Test[1]
+	test.Equals(t, (*Auth)(nil), own.Auth)
+	test.Equals(t, map[string]string{"authorization": "Bearer own"}, own.Headers)
+	test.Equals(t, &Auth{Bearer: "default"}, inherited.Auth)
+}
+
+func TestAuthHeader(t *testing.T) {
+	var none *Auth
+	test.Equals(t, "", none.Header())
+	test.Equals(t, "Bearer abc", (&Auth{Bearer: "abc"}).Header())
+	test.Equals(t, "Basic dXNlcjpwYXNz", (&Auth{Username: "user", Password: "pass"}).Header())
+}
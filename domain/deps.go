@@ -0,0 +1,224 @@
+package domain
+
+import (
+	"sort"
+	"text/template/parse"
+)
+
+// EnvRefs returns the environment keys referenced by the templates
+// in the test, sorted by name, leaving out those provided by the
+// test's own vars. Templates that can't be parsed are ignored here;
+// they're reported when the test is run.
+func (r *RequestTest) EnvRefs() []string {
+	refs := map[string]bool{}
+	r.walkTemplates(true, true, func(s string) {
+		strict, optional := templateRefs(s)
+		for _, k := range append(strict, optional...) {
+			refs[k] = true
+		}
+	})
+	return r.withoutVars(refs)
+}
+
+// RequestRefs returns the environment keys the test's request can't
+// be built without, sorted by name. Keys only passed to the default
+// and required functions are left out, as are the test's own vars.
+func (r *RequestTest) RequestRefs() []string {
+	return r.strictRefs(true, false)
+}
+
+// AssertionRefs returns the environment keys the test's assertions
+// can't be evaluated without, in the same way as RequestRefs.
+func (r *RequestTest) AssertionRefs() []string {
+	return r.strictRefs(false, true)
+}
+
+func (r *RequestTest) strictRefs(request, assertions bool) []string {
+	refs := map[string]bool{}
+	r.walkTemplates(request, assertions, func(s string) {
+		strict, _ := templateRefs(s)
+		for _, k := range strict {
+			refs[k] = true
+		}
+	})
+	return r.withoutVars(refs)
+}
+
+// walkTemplates calls fn with each template in the test's request,
+// its assertions, or both.
+func (r *RequestTest) walkTemplates(request, assertions bool, fn func(string)) {
+	if request {
+		fn(r.URL)
+		fn(r.Payload)
+		for _, m := range []map[string]string{r.Headers, r.Query, r.Cookies} {
+			for _, v := range m {
+				fn(v)
+			}
+		}
+		if r.Auth != nil {
+			fn(r.Auth.Bearer)
+			fn(r.Auth.Username)
+			fn(r.Auth.Password)
+		}
+	}
+	if assertions {
+		for _, m := range []map[string]interface{}{r.Body, r.Head} {
+			for k, v := range m {
+				fn(k)
+				walkStrings(v, fn)
+			}
+		}
+		for _, gc := range r.Getters {
+			fn(gc.Path)
+			walkStrings(gc.Expected, fn)
+		}
+	}
+}
+
+func (r *RequestTest) withoutVars(refs map[string]bool) []string {
+	for k := range r.Vars {
+		delete(refs, k)
+	}
+	return sortedKeys(refs)
+}
+
+// SetKeys returns the environment keys the test captures values
+// into, sorted by name.
+func (r *RequestTest) SetKeys() []string {
+	keys := map[string]bool{}
+	for _, m := range []map[string]interface{}{r.Body, r.Head} {
+		for k, v := range m {
+			if a, err := extractAssertion(k, v); err == nil && a.Set != "" {
+				keys[a.Set] = true
+			}
+		}
+	}
+	for _, gc := range r.Getters {
+		if gc.Set != "" {
+			keys[gc.Set] = true
+		}
+	}
+
+	return sortedKeys(keys)
+}
+
+// optionalFuncs are the template functions that handle missing
+// values themselves, so keys only passed to them may be missing.
+var optionalFuncs = map[string]bool{"default": true, "required": true}
+
+// templateRefs returns the top level fields referenced by a
+// template, such as base_url in {{.base_url}}. Fields that are only
+// passed to the default or required functions are returned as
+// optional, the rest as strict.
+func templateRefs(s string) (strict, optional []string) {
+	t, err := newTemplate(s)
+	if err != nil || t.Tree == nil {
+		return nil, nil
+	}
+
+	add := func(n parse.Node, isOptional bool) {
+		f, ok := n.(*parse.FieldNode)
+		if !ok {
+			return
+		}
+		if isOptional {
+			optional = append(optional, f.Ident[0])
+		} else {
+			strict = append(strict, f.Ident[0])
+		}
+	}
+
+	var walk func(n parse.Node)
+	walk = func(n parse.Node) {
+		switch x := n.(type) {
+		case *parse.ListNode:
+			if x == nil {
+				return
+			}
+			for _, c := range x.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(x.Pipe)
+		case *parse.PipeNode:
+			if x == nil {
+				return
+			}
+			for i, c := range x.Cmds {
+				// A lone field piped into default or required,
+				// such as {{.name | default "x"}}.
+				if i+1 < len(x.Cmds) && len(c.Args) == 1 && isOptionalCall(x.Cmds[i+1]) {
+					if _, ok := c.Args[0].(*parse.FieldNode); ok {
+						add(c.Args[0], true)
+						continue
+					}
+				}
+				walk(c)
+			}
+		case *parse.CommandNode:
+			optionalArgs := isOptionalCall(x)
+			for _, a := range x.Args {
+				if _, ok := a.(*parse.FieldNode); ok {
+					add(a, optionalArgs)
+					continue
+				}
+				walk(a)
+			}
+		case *parse.ChainNode:
+			walk(x.Node)
+		case *parse.IfNode:
+			walk(x.Pipe)
+			walk(x.List)
+			walk(x.ElseList)
+		case *parse.RangeNode:
+			walk(x.Pipe)
+			walk(x.List)
+			walk(x.ElseList)
+		case *parse.WithNode:
+			walk(x.Pipe)
+			walk(x.List)
+			walk(x.ElseList)
+		case *parse.TemplateNode:
+			walk(x.Pipe)
+		}
+	}
+	walk(t.Tree.Root)
+	return strict, optional
+}
+
+func isOptionalCall(c *parse.CommandNode) bool {
+	if len(c.Args) == 0 {
+		return false
+	}
+	id, ok := c.Args[0].(*parse.IdentifierNode)
+	return ok && optionalFuncs[id.Ident]
+}
+
+// walkStrings calls fn for every string in v, including those
+// nested in lists and maps.
+func walkStrings(v interface{}, fn func(string)) {
+	switch x := v.(type) {
+	case string:
+		fn(x)
+	case []interface{}:
+		for _, item := range x {
+			walkStrings(item, fn)
+		}
+	case map[interface{}]interface{}:
+		walkStrings(convertInterfaceMap(x), fn)
+	case map[string]interface{}:
+		for k, item := range x {
+			fn(k)
+			walkStrings(item, fn)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
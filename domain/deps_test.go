@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestEnvRefs(t *testing.T) {
+	r := &RequestTest{
+		URL:     "{{.base_url}}/users/{{.user_id}}",
+		Payload: `{"name":"{{.name}}"}`,
+		Headers: map[string]string{"Authorization": "Bearer {{.token}}"},
+		Query:   map[string]string{"page": "{{if .page}}{{.page}}{{end}}"},
+		Cookies: map[string]string{"session": "{{.session}}"},
+		Body: map[string]interface{}{
+			"id":   "{{.user_id}}",
+			"tags": []interface{}{"{{.tag}}"},
+		},
+		Getters: GetterConfigs{
+			{Type: "header", Path: "{{.header}}", Expected: "x"},
+		},
+	}
+
+	test.Equals(t, []string{"base_url", "header", "name", "page", "session", "tag", "token", "user_id"}, r.EnvRefs())
+}
+
+func TestSetKeys(t *testing.T) {
+	r := &RequestTest{
+		Body: map[string]interface{}{
+			"user_id": map[string]interface{}{"id": ""},
+			"name":    "bob",
+			"email":   map[string]interface{}{"op": "exists", "set": "email"},
+		},
+		Head: map[string]interface{}{
+			"token": map[string]interface{}{"X-Token": ""},
+		},
+		Getters: GetterConfigs{
+			{Type: "status", Set: "status"},
+		},
+	}
+
+	test.Equals(t, []string{"email", "status", "token", "user_id"}, r.SetKeys())
+}
+
+func TestTemplateRefs(t *testing.T) {
+	tests := []struct {
+		tpl      string
+		strict   []string
+		optional []string
+	}{
+		{tpl: "{{.a}}/{{.b.c}}", strict: []string{"a", "b"}},
+		{tpl: `{{.a | default "x"}}`, optional: []string{"a"}},
+		{tpl: `{{default "x" .a}}`, optional: []string{"a"}},
+		{tpl: `{{.a | required "a is required"}}`, optional: []string{"a"}},
+		{tpl: `{{.a | upper | default "x"}}`, strict: []string{"a"}},
+		{tpl: `{{if .a}}{{.b}}{{end}}`, strict: []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tpl, func(t *testing.T) {
+			strict, optional := templateRefs(tt.tpl)
+			test.Equals(t, tt.strict, strict)
+			test.Equals(t, tt.optional, optional)
+		})
+	}
+}
+
+func TestRequestAndAssertionRefs(t *testing.T) {
+	r := &RequestTest{
+		URL:  `{{.base_url}}/{{.id}}?q={{.q | default ""}}`,
+		Body: map[string]interface{}{"name": "{{.name}}"},
+		Vars: map[string]interface{}{"id": 1},
+	}
+	test.Equals(t, []string{"base_url"}, r.RequestRefs())
+	test.Equals(t, []string{"name"}, r.AssertionRefs())
+	test.Equals(t, []string{"base_url", "name", "q"}, r.EnvRefs())
+}
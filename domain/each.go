@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Each describes the rows a test is repeated for. Rows are given
+// inline or loaded from a CSV, JSON or YAML file, relative to the
+// test file. Each row's fields are available to the test's
+// templates.
+type Each struct {
+	Rows []map[string]interface{} `toml:"rows" yaml:"rows"`
+	File string                   `toml:"file" yaml:"file"`
+
+	// ID names the field identifying each row in the names of
+	// the generated tests. Rows are numbered if it isn't set.
+	ID string `toml:"id" yaml:"id"`
+}
+
+// Expand replaces each test that has an each or matrix table with
+// one test per row or combination of values.
+func (tf *TestFile) Expand() error {
+	var expanded []RequestTest
+	for _, r := range tf.Litmus.Test {
+		tests, err := r.expand(filepath.Dir(tf.Path))
+		if err != nil {
+			return errors.Wrapf(err, "expanding test %q", r.Name)
+		}
+		expanded = append(expanded, tests...)
+	}
+	tf.Litmus.Test = expanded
+	return nil
+}
+
+// expand returns the tests generated from r, or r itself if it
+// isn't data-driven. dir is the folder row files are read from.
+func (r RequestTest) expand(dir string) ([]RequestTest, error) {
+	if r.Each == nil && len(r.Matrix) == 0 {
+		return []RequestTest{r}, nil
+	}
+
+	type row struct {
+		id   string
+		vars map[string]interface{}
+	}
+	rows := []row{{vars: map[string]interface{}{}}}
+
+	if r.Each != nil {
+		eachRows, err := r.Each.rows(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = rows[:0]
+		for i, vars := range eachRows {
+			id := strconv.Itoa(i + 1)
+			if r.Each.ID != "" {
+				v, ok := vars[r.Each.ID]
+				if !ok {
+					return nil, errors.Errorf("row %d has no %q field", i+1, r.Each.ID)
+				}
+				id = stringify(v)
+			}
+			rows = append(rows, row{id: id, vars: vars})
+		}
+	}
+
+	keys := make([]string, 0, len(r.Matrix))
+	for k := range r.Matrix {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var combined []row
+		for _, base := range rows {
+			for _, v := range r.Matrix[k] {
+				vars := make(map[string]interface{}, len(base.vars)+1)
+				for bk, bv := range base.vars {
+					vars[bk] = bv
+				}
+				vars[k] = normalizeNumbers(v)
+
+				id := stringify(vars[k])
+				if base.id != "" {
+					id = base.id + ", " + id
+				}
+				combined = append(combined, row{id: id, vars: vars})
+			}
+		}
+		rows = combined
+	}
+
+	tests := make([]RequestTest, 0, len(rows))
+	for _, row := range rows {
+		t := r
+		t.Name = fmt.Sprintf("%s [%s]", r.Name, row.id)
+		t.baseName = r.Name
+		t.Each, t.Matrix = nil, nil
+		t.Vars = make(map[string]interface{}, len(r.Vars)+len(row.vars))
+		for k, v := range r.Vars {
+			t.Vars[k] = v
+		}
+		for k, v := range row.vars {
+			t.Vars[k] = v
+		}
+		tests = append(tests, t)
+	}
+	return tests, nil
+}
+
+// rows returns the inline rows followed by those in the file.
+func (e *Each) rows(dir string) ([]map[string]interface{}, error) {
+	rows := make([]map[string]interface{}, 0, len(e.Rows))
+	for _, row := range e.Rows {
+		rows = append(rows, normalizeRow(row))
+	}
+	if e.File == "" {
+		return rows, nil
+	}
+
+	path := e.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	fileRows, err := readRows(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading rows from %s", e.File)
+	}
+	return append(rows, fileRows...), nil
+}
+
+// readRows reads rows from a CSV file, whose first line holds the
+// field names, or from a JSON or YAML file holding a list of objects.
+// CSV fields are always strings.
+func readRows(path string) (rows []map[string]interface{}, err error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 {
+			return nil, nil
+		}
+		for _, record := range records[1:] {
+			row := make(map[string]interface{}, len(records[0]))
+			for i, field := range records[0] {
+				row[field] = record[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	case ".json":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &rows); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var items []map[string]interface{}
+		if err = yaml.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		rows = items
+	default:
+		return nil, errors.Errorf("unsupported file type %q", filepath.Ext(path))
+	}
+
+	for i, row := range rows {
+		rows[i] = normalizeRow(row)
+	}
+	return rows, nil
+}
+
+func normalizeRow(row map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		out[k] = normalizeNumbers(v)
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestExpand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "litmus")
+	test.ErrorNil(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"products.csv":  "sku,name\nbb-1,bath bomb\nsp-2,soap\n",
+		"products.json": `[{"sku":"bb-1","price":3.5,"stock":10}]`,
+		"products.yaml": "- {sku: bb-1, stock: 10}\n",
+	}
+	for name, data := range files {
+		test.ErrorNil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+
+	tests := []struct {
+		name      string
+		test      RequestTest
+		expNames  []string
+		expVars   []map[string]interface{}
+		expErrMsg string
+	}{
+		{
+			name:     "plain",
+			test:     RequestTest{Name: "get"},
+			expNames: []string{"get"},
+			expVars:  []map[string]interface{}{nil},
+		},
+		{
+			name: "inline rows",
+			test: RequestTest{Name: "get", Vars: map[string]interface{}{"v": 1}, Each: &Each{Rows: []map[string]interface{}{
+				{"sku": "a", "price": float64(2)},
+				{"sku": "b", "price": 2.5},
+			}}},
+			expNames: []string{"get [1]", "get [2]"},
+			expVars: []map[string]interface{}{
+				{"v": 1, "sku": "a", "price": int64(2)},
+				{"v": 1, "sku": "b", "price": 2.5},
+			},
+		},
+		{
+			name:     "csv with id",
+			test:     RequestTest{Name: "get", Each: &Each{File: "products.csv", ID: "sku"}},
+			expNames: []string{"get [bb-1]", "get [sp-2]"},
+			expVars: []map[string]interface{}{
+				{"sku": "bb-1", "name": "bath bomb"},
+				{"sku": "sp-2", "name": "soap"},
+			},
+		},
+		{
+			name:     "json",
+			test:     RequestTest{Name: "get", Each: &Each{File: "products.json", ID: "sku"}},
+			expNames: []string{"get [bb-1]"},
+			expVars:  []map[string]interface{}{{"sku": "bb-1", "price": 3.5, "stock": int64(10)}},
+		},
+		{
+			name:     "yaml",
+			test:     RequestTest{Name: "get", Each: &Each{File: "products.yaml", ID: "sku"}},
+			expNames: []string{"get [bb-1]"},
+			expVars:  []map[string]interface{}{{"sku": "bb-1", "stock": int64(10)}},
+		},
+		{
+			name: "matrix",
+			test: RequestTest{Name: "get", Matrix: map[string][]interface{}{
+				"lang":     {"en", "fr"},
+				"currency": {"GBP"},
+			}},
+			expNames: []string{"get [GBP, en]", "get [GBP, fr]"},
+			expVars: []map[string]interface{}{
+				{"currency": "GBP", "lang": "en"},
+				{"currency": "GBP", "lang": "fr"},
+			},
+		},
+		{
+			name: "rows and matrix",
+			test: RequestTest{Name: "get",
+				Each:   &Each{Rows: []map[string]interface{}{{"sku": "a"}}, ID: "sku"},
+				Matrix: map[string][]interface{}{"lang": {"en", "fr"}},
+			},
+			expNames: []string{"get [a, en]", "get [a, fr]"},
+			expVars: []map[string]interface{}{
+				{"sku": "a", "lang": "en"},
+				{"sku": "a", "lang": "fr"},
+			},
+		},
+		{
+			name:      "missing id",
+			test:      RequestTest{Name: "get", Each: &Each{Rows: []map[string]interface{}{{"sku": "a"}}, ID: "id"}},
+			expErrMsg: `expanding test "get": row 1 has no "id" field`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tf := TestFile{Path: filepath.Join(dir, "a_test.toml")}
+			tf.Litmus.Test = []RequestTest{tt.test}
+
+			err := tf.Expand()
+			if tt.expErrMsg != "" {
+				test.Assert(t, err != nil)
+				test.Equals(t, tt.expErrMsg, err.Error())
+				return
+			}
+			test.ErrorNil(t, err)
+
+			var names []string
+			var vars []map[string]interface{}
+			for _, r := range tf.Litmus.Test {
+				names = append(names, r.Name)
+				vars = append(vars, r.Vars)
+				test.Assert(t, r.Each == nil && r.Matrix == nil)
+				test.Equals(t, tt.test.Name, r.BaseName())
+			}
+			test.Equals(t, tt.expNames, names)
+			test.Equals(t, tt.expVars, vars)
+		})
+	}
+}
+
+func TestEnvRefsIgnoresVars(t *testing.T) {
+	r := &RequestTest{URL: "{{.base}}/products/{{.sku}}", Vars: map[string]interface{}{"sku": "a"}}
+	test.Equals(t, []string{"base"}, r.EnvRefs())
+}
@@ -0,0 +1,255 @@
+package domain
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Include adds the sections of the files listed in the file's
+// include to it, ahead of its own, recursively. Paths are relative
+// to the including file. The file's own templates take precedence
+// over included ones, and its defaults over theirs. load decodes
+// an included file.
+func (tf *TestFile) Include(load func(path string) (*TestFile, error)) error {
+	return tf.include(load, []string{tf.Path})
+}
+
+func (tf *TestFile) include(load func(path string) (*TestFile, error), stack []string) error {
+	dir := filepath.Dir(tf.Path)
+	for i := len(tf.Litmus.Include) - 1; i >= 0; i-- {
+		path := tf.Litmus.Include[i]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		for _, p := range stack {
+			if SamePath(p, path) {
+				return errors.Errorf("include cycle: %s", strings.Join(append(stack, path), " -> "))
+			}
+		}
+
+		inc, err := load(path)
+		if err != nil {
+			return errors.Wrapf(err, "including %s", path)
+		}
+		if err = inc.include(load, append(stack, path)); err != nil {
+			return err
+		}
+		inc.rebase()
+
+		l, il := &tf.Litmus, &inc.Litmus
+		l.Test = append(il.Test, l.Test...)
+		l.Setup = append(il.Setup, l.Setup...)
+		l.Teardown = append(il.Teardown, l.Teardown...)
+		l.Request = append(il.Request, l.Request...)
+		for name, t := range il.Templates {
+			if _, ok := l.Templates[name]; !ok {
+				if l.Templates == nil {
+					l.Templates = make(map[string]RequestTest)
+				}
+				l.Templates[name] = t
+			}
+		}
+		inheritFields(reflect.ValueOf(&l.Defaults).Elem(), reflect.ValueOf(il.Defaults))
+	}
+	tf.Litmus.Include = nil
+	return nil
+}
+
+// rebase makes the row files of the file's requests and templates
+// absolute, so they're still found once the requests are included
+// in, or extended by, a file in another folder.
+func (tf *TestFile) rebase() {
+	dir := filepath.Dir(tf.Path)
+	rebase := func(r *RequestTest) {
+		if r.Each == nil || r.Each.File == "" || filepath.IsAbs(r.Each.File) {
+			return
+		}
+		each := *r.Each
+		if abs, err := filepath.Abs(filepath.Join(dir, each.File)); err == nil {
+			each.File = abs
+		}
+		r.Each = &each
+	}
+
+	for _, section := range []string{SectionTest, SectionSetup, SectionTeardown, SectionRequest} {
+		requests := tf.Litmus.Section(section)
+		for i := range requests {
+			rebase(&requests[i])
+		}
+	}
+	for name, t := range tf.Litmus.Templates {
+		rebase(&t)
+		tf.Litmus.Templates[name] = t
+	}
+}
+
+// SamePath reports whether a and b name the same file, comparing
+// their absolute paths, or if those can't be found, the paths
+// themselves.
+func SamePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// Extend fills in the fields of each request that names a template
+// in extends from that template. Templates are looked up in the
+// file's templates, then in shared, such as those of the suite and
+// the fragments folder. Templates can themselves extend others, and
+// a file's template can extend the shared template it overrides by
+// extending its own name.
+func (tf *TestFile) Extend(shared map[string]RequestTest) error {
+	templates := make(map[string]RequestTest, len(tf.Litmus.Templates))
+	for name, t := range tf.Litmus.Templates {
+		if base, ok := shared[name]; ok && t.Extends == name {
+			err := t.extend(func(string) (RequestTest, bool) { return base, true }, nil)
+			if err != nil {
+				return errors.Wrapf(err, "template %q", name)
+			}
+		}
+		templates[name] = t
+	}
+	lookup := func(name string) (RequestTest, bool) {
+		if t, ok := templates[name]; ok {
+			return t, true
+		}
+		t, ok := shared[name]
+		return t, ok
+	}
+
+	for _, section := range []string{SectionSetup, SectionTest, SectionTeardown, SectionRequest} {
+		requests := tf.Litmus.Section(section)
+		for i := range requests {
+			if err := requests[i].extend(lookup, nil); err != nil {
+				return errors.Wrapf(err, "%s %q", section, requests[i].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// SharedTemplates collects the templates of files, such as the
+// suite file and those in the fragments folder, that every test
+// file can extend. A name may only be defined once.
+func SharedTemplates(files ...*TestFile) (map[string]RequestTest, error) {
+	templates := make(map[string]RequestTest)
+	defined := make(map[string]string)
+	for _, tf := range files {
+		tf.rebase()
+		for name, t := range tf.Litmus.Templates {
+			if path, ok := defined[name]; ok {
+				return nil, errors.Errorf("template %q is defined in both %s and %s", name, path, tf.Path)
+			}
+			templates[name] = t
+			defined[name] = tf.Path
+		}
+	}
+
+	lookup := func(name string) (RequestTest, bool) {
+		t, ok := templates[name]
+		return t, ok
+	}
+	resolved := make(map[string]RequestTest, len(templates))
+	for _, name := range sortedTemplateNames(templates) {
+		t := templates[name]
+		if err := t.extend(lookup, []string{name}); err != nil {
+			return nil, errors.Wrapf(err, "%s: template %q", defined[name], name)
+		}
+		resolved[name] = t
+	}
+	return resolved, nil
+}
+
+// extend fills in r's fields from the template it extends, after
+// resolving that template's own extends. chain holds the templates
+// being resolved, to detect cycles.
+func (r *RequestTest) extend(lookup func(string) (RequestTest, bool), chain []string) error {
+	if r.Extends == "" {
+		return nil
+	}
+	for _, name := range chain {
+		if name == r.Extends {
+			return errors.Errorf("extends cycle: %s", strings.Join(append(chain, r.Extends), " -> "))
+		}
+	}
+
+	base, ok := lookup(r.Extends)
+	if !ok {
+		return errors.Errorf("extends unknown template %q", r.Extends)
+	}
+	if err := base.extend(lookup, append(chain, r.Extends)); err != nil {
+		return err
+	}
+
+	r.Extends = ""
+	inheritFields(reflect.ValueOf(r).Elem(), reflect.ValueOf(base))
+	r.bodyOrder = mergeOrder(base.bodyOrder, r.bodyOrder)
+	r.headOrder = mergeOrder(base.headOrder, r.headOrder)
+	return nil
+}
+
+// inheritFields sets the exported fields of dst that are unset from
+// src. Maps are merged, with dst's entries taking precedence, and
+// maps and pointers are copied so the two don't share values. A
+// field is unset if it holds its zero value, so dst can't override
+// a value of src with a zero value.
+func inheritFields(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Type().Field(i).PkgPath != "" {
+			continue
+		}
+		d, s := dst.Field(i), src.Field(i)
+		switch {
+		case isZero(s):
+		case d.Kind() == reflect.Map:
+			merged := reflect.MakeMap(d.Type())
+			for _, m := range []reflect.Value{s, d} {
+				for _, k := range m.MapKeys() {
+					merged.SetMapIndex(k, m.MapIndex(k))
+				}
+			}
+			d.Set(merged)
+		case !isZero(d):
+		case s.Kind() == reflect.Ptr:
+			p := reflect.New(s.Type().Elem())
+			p.Elem().Set(s.Elem())
+			d.Set(p)
+		default:
+			d.Set(s)
+		}
+	}
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// mergeOrder returns the keys in base, followed by those in order
+// that aren't.
+func mergeOrder(base, order []string) []string {
+	merged := append([]string(nil), base...)
+	for _, k := range order {
+		merged = appendUnique(merged, k)
+	}
+	return merged
+}
+
+func sortedTemplateNames(templates map[string]RequestTest) []string {
+	names := make(map[string]bool, len(templates))
+	for name := range templates {
+		names[name] = true
+	}
+	return sortedKeys(names)
+}
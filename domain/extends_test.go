@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+	"github.com/pkg/errors"
+)
+
+func TestExtend(t *testing.T) {
+	login := RequestTest{
+		Name:      "log in",
+		Method:    "POST",
+		URL:       "{{.base}}/login",
+		Headers:   map[string]string{"Content-Type": "application/json", "X-Client": "litmus"},
+		Payload:   `{"user": "{{.user}}"}`,
+		WantsCode: 200,
+		Body:      map[string]interface{}{"token": map[string]interface{}{"token": "abc"}},
+		Auth:      &Auth{Username: "admin"},
+		bodyOrder: []string{"token"},
+	}
+
+	tf := TestFile{Litmus: Litmus{
+		Templates: map[string]RequestTest{
+			"admin login": {Extends: "login", Vars: map[string]interface{}{"user": "admin"}},
+		},
+		Test: []RequestTest{{
+			Name:      "log in as admin",
+			Extends:   "admin login",
+			Headers:   map[string]string{"X-Client": "tests"},
+			Body:      map[string]interface{}{"expires": "3600"},
+			bodyOrder: []string{"expires"},
+		}},
+	}}
+	test.ErrorNil(t, tf.Extend(map[string]RequestTest{"login": login}))
+
+	got := tf.Litmus.Test[0]
+	test.Equals(t, "log in as admin", got.Name)
+	test.Equals(t, "", got.Extends)
+	test.Equals(t, "POST", got.Method)
+	test.Equals(t, "{{.base}}/login", got.URL)
+	test.Equals(t, 200, got.WantsCode)
+	test.Equals(t, map[string]string{"Content-Type": "application/json", "X-Client": "tests"}, got.Headers)
+	test.Equals(t, map[string]interface{}{"user": "admin"}, got.Vars)
+	test.Equals(t, []string{"token", "expires"}, orderedKeys(got.Body, got.bodyOrder))
+
+	// The template's maps and pointers aren't shared.
+	got.Auth.Username = "changed"
+	got.Body["extra"] = "1"
+	test.Equals(t, "admin", login.Auth.Username)
+	test.Equals(t, 1, len(login.Body))
+}
+
+func TestExtendOverriddenTemplate(t *testing.T) {
+	shared := map[string]RequestTest{
+		"login": {Method: "POST", URL: "/login", WantsCode: 200},
+	}
+	tf := TestFile{Litmus: Litmus{
+		Templates: map[string]RequestTest{
+			"login":       {Extends: "login", WantsCode: 201},
+			"admin login": {Extends: "login", Vars: map[string]interface{}{"user": "admin"}},
+		},
+		Test: []RequestTest{
+			{Name: "log in", Extends: "login"},
+			{Name: "log in as admin", Extends: "admin login"},
+		},
+	}}
+	test.ErrorNil(t, tf.Extend(shared))
+
+	for _, got := range tf.Litmus.Test {
+		test.Equals(t, "POST", got.Method)
+		test.Equals(t, "/login", got.URL)
+		test.Equals(t, 201, got.WantsCode)
+	}
+}
+
+func TestExtendErrors(t *testing.T) {
+	cases := []struct {
+		name      string
+		templates map[string]RequestTest
+		test      RequestTest
+		exp       string
+	}{
+		{
+			name: "unknown",
+			test: RequestTest{Name: "get", Extends: "missing"},
+			exp:  `test "get": extends unknown template "missing"`,
+		},
+		{
+			name: "cycle",
+			templates: map[string]RequestTest{
+				"a": {Extends: "b"},
+				"b": {Extends: "a"},
+			},
+			test: RequestTest{Name: "get", Extends: "a"},
+			exp:  `test "get": extends cycle: a -> b -> a`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tf := TestFile{Litmus: Litmus{Templates: c.templates, Test: []RequestTest{c.test}}}
+			err := tf.Extend(nil)
+			test.Assert(t, err != nil)
+			test.Equals(t, c.exp, err.Error())
+		})
+	}
+}
+
+func TestSharedTemplates(t *testing.T) {
+	a := &TestFile{Path: "fragments/a.toml", Litmus: Litmus{Templates: map[string]RequestTest{
+		"login":       {Method: "POST", URL: "/login"},
+		"admin login": {Extends: "login", Vars: map[string]interface{}{"user": "admin"}},
+	}}}
+	templates, err := SharedTemplates(a)
+	test.ErrorNil(t, err)
+	test.Equals(t, "/login", templates["admin login"].URL)
+
+	b := &TestFile{Path: "suite.toml", Litmus: Litmus{Templates: map[string]RequestTest{"login": {}}}}
+	_, err = SharedTemplates(a, b)
+	test.Assert(t, err != nil)
+	test.Equals(t, `template "login" is defined in both fragments/a.toml and suite.toml`, err.Error())
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]TestFile{
+		"tests/shared/auth.toml": {Litmus: Litmus{
+			Include:   []string{"base.toml"},
+			Setup:     []RequestTest{{Name: "log in"}},
+			Templates: map[string]RequestTest{"login": {URL: "/shared"}},
+			Defaults:  Defaults{BaseURL: "http://shared", Method: "POST"},
+		}},
+		"tests/shared/base.toml": {Litmus: Litmus{
+			Request: []RequestTest{{Name: "reset"}},
+		}},
+		"tests/loop.toml": {Litmus: Litmus{Include: []string{"loop.toml"}}},
+	}
+	load := func(path string) (*TestFile, error) {
+		tf, ok := files[path]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		tf.Path = path
+		return &tf, nil
+	}
+
+	tf := TestFile{Path: "tests/a_test.toml", Litmus: Litmus{
+		Include:   []string{"shared/auth.toml"},
+		Setup:     []RequestTest{{Name: "create order"}},
+		Request:   []RequestTest{{Name: "log out"}},
+		Templates: map[string]RequestTest{"login": {URL: "/own"}},
+		Defaults:  Defaults{BaseURL: "http://own"},
+	}}
+	test.ErrorNil(t, tf.Include(load))
+
+	names := func(requests []RequestTest) (names []string) {
+		for _, r := range requests {
+			names = append(names, r.Name)
+		}
+		return
+	}
+	test.Equals(t, []string{"log in", "create order"}, names(tf.Litmus.Setup))
+	test.Equals(t, []string{"reset", "log out"}, names(tf.Litmus.Request))
+	test.Equals(t, "/own", tf.Litmus.Templates["login"].URL)
+	test.Equals(t, Defaults{BaseURL: "http://own", Method: "POST"}, tf.Litmus.Defaults)
+
+	loop := TestFile{Path: "tests/loop.toml", Litmus: Litmus{Include: []string{"loop.toml"}}}
+	err := loop.Include(load)
+	test.Assert(t, err != nil)
+	test.Equals(t, "include cycle: tests/loop.toml -> tests/loop.toml", err.Error())
+
+	missing := TestFile{Path: "tests/a_test.toml", Litmus: Litmus{Include: []string{"missing.toml"}}}
+	err = missing.Include(load)
+	test.Assert(t, err != nil)
+	test.Equals(t, "including tests/missing.toml: not found", err.Error())
+}
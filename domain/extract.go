@@ -11,21 +11,26 @@ package domain
 
 import (
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
 	"github.com/pkg/errors"
 )
 
+// ProcessResponse runs every status, header and body assertion against
+// the response, returning all failures together as AssertionErrors.
+// Captures are still applied for the assertions that passed.
 func ProcessResponse(r *RequestTest, resp *http.Response, env map[string]interface{}) error {
-	if err := StatusCode(r, resp, env); err != nil {
-		return err
-	}
-	if err := Header(r, resp, env); err != nil {
-		return err
+	if resp == nil {
+		return errors.New("unexpected nil response")
 	}
 
-	return Body(r, resp, env)
+	var errs AssertionErrors
+	errs = errs.Append(SourceStatus, StatusCode(r, resp, env))
+	errs = errs.Append(SourceHeader, Header(r, resp, env))
+	errs = errs.Append(SourceBody, Body(r, resp, env))
+	errs = errs.Append(SourceGetter, Getters(r, resp, env))
+
+	return errs.ErrOrNil()
 }
 
 // StatusCode - extracts the status code and checks it against the expected value
@@ -34,10 +39,11 @@ func StatusCode(r *RequestTest, resp *http.Response, _ map[string]interface{}) e
 		return errors.New("unexpected nil response")
 	}
 	if r.WantsCode != 0 && r.WantsCode != resp.StatusCode {
-		return errors.Errorf("expected response code: %s, but got: %s",
-			http.StatusText(r.WantsCode),
-			http.StatusText(resp.StatusCode),
-		)
+		return AssertionErrors{{
+			Source:   SourceStatus,
+			Expected: statusText(r.WantsCode),
+			Actual:   statusText(resp.StatusCode),
+		}}
 	}
 	return nil
 }
@@ -48,71 +54,104 @@ func Body(r *RequestTest, resp *http.Response, env map[string]interface{}) error
 		return errors.New("unexpected nil response")
 	}
 
+	// Without any body assertions, there's no need to
+	// understand the body at all.
+	if len(r.Body) == 0 {
+		return nil
+	}
+
 	// If we're unable to ascertain the body type, we won't
 	// be able to extract anything and needn't bother reading
 	// the response body.
-	bodyGetter, err := NewBodyGetter(resp)
+	bodyGetter, err := newBodyGetter(r, resp)
 	if err != nil {
 		return errors.Wrap(err, "creating body getter")
 	}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := readBody(resp)
 	if err != nil {
 		return errors.Wrap(err, "reading response body")
 	}
-	defer resp.Body.Close()
 
-	for k, v := range r.Body {
-		path, expected, set, err := extractParam(k, v)
+	var errs AssertionErrors
+	for _, k := range orderedKeys(r.Body, r.bodyOrder) {
+		a, err := entryAssertion(k, r.Body[k], env)
 		if err != nil {
-			return errors.Wrap(err, "extracting body param")
+			errs = append(errs, &AssertionError{Source: SourceBody, Path: k, Err: errors.Wrap(err, "extracting body param")})
+			continue
+		}
+
+		if !isTyped(bodyGetter) {
+			a.stringOperands()
 		}
 
-		actual, err := bodyGetter.Get(path, respBody)
+		actual, found, err := getBodyValue(bodyGetter, &a, respBody)
 		if err != nil {
-			return err
+			errs = append(errs, &AssertionError{Source: SourceBody, Path: a.Path, Op: a.Op, Expected: a.expectation(), Err: err})
+			continue
 		}
 
-		if err = equals(expected, actual); err != nil {
-			return errors.Wrap(err, "assertion failed")
+		if aerr := a.evaluate(SourceBody, actual, found); aerr != nil {
+			errs = append(errs, aerr)
+			continue
 		}
 
-		if set != "" {
-			env[set] = actual
+		if a.Set != "" && found {
+			env[a.Set] = actual
 		}
 	}
 
-	return nil
+	return errs.ErrOrNil()
+}
+
+// getBodyValue returns the value at the assertion's path. A
+// missing value isn't an error, but is reported through found.
+func getBodyValue(g BodyGetter, a *Assertion, body []byte) (value interface{}, found bool, err error) {
+	value, err = g.Get(a.Path, body)
+	if IsNoValue(err) {
+		return nil, false, nil
+	}
+	return value, err == nil, err
+}
+
+// entryAssertion applies the environment, including values
+// captured by earlier assertions, to a head or body entry and
+// builds its Assertion.
+func entryAssertion(key string, value interface{}, env map[string]interface{}) (Assertion, error) {
+	key, value, err := applyEntryEnv(key, value, env)
+	if err != nil {
+		return Assertion{}, errors.Wrap(err, "applying environment")
+	}
+	return extractAssertion(key, value)
 }
 
-func getFirst(m map[string]interface{}) (key, val string, err error) {
+func newBodyGetter(r *RequestTest, resp *http.Response) (BodyGetter, error) {
+	if r.BodyType != "" {
+		return NewBodyGetterForType(r.BodyType)
+	}
+	return NewBodyGetter(resp)
+}
+
+func getFirst(m map[string]interface{}) (key string, val interface{}) {
 	for k, v := range m {
-		val, ok := v.(string)
-		if !ok {
-			return "", "", errors.Errorf("expected string but got: %T", val)
-		}
-		return k, val, nil
+		return k, v
 	}
-	return "", "", nil
+	return "", nil
 }
 
-func extractParam(key string, value interface{}) (path, expected, set string, err error) {
+// extractParam returns the path, expected value and set key of a
+// head or body entry. The expected value keeps the type it was
+// written with.
+func extractParam(key string, value interface{}) (path string, expected interface{}, set string, err error) {
 	switch x := value.(type) {
 	case map[string]interface{}: // TOML unmarshals to this.
-		if path, expected, err = getFirst(x); err != nil {
-			return
-		}
-		return path, expected, key, err
+		path, expected = getFirst(x)
+		return path, expected, key, nil
 	case map[interface{}]interface{}: // YAML unmarshals to this.
-		ms := convertInterfaceMap(x)
-		if path, expected, err = getFirst(ms); err != nil {
-			return
-		}
-		return path, expected, key, err
-	case string:
-		return key, x, "", err
+		path, expected = getFirst(convertInterfaceMap(x))
+		return path, expected, key, nil
 	default:
-		return "", "", "", errors.Errorf("expected string but got: %T", x)
+		return key, x, "", nil
 	}
 }
 
@@ -130,32 +169,36 @@ func Header(r *RequestTest, resp *http.Response, env map[string]interface{}) err
 		return errors.New("unexpected nil response")
 	}
 
-	headerGetter := &HeaderGetter{}
-
-	for k, v := range r.Head {
-		path, expected, set, err := extractParam(k, v)
+	var errs AssertionErrors
+	for _, k := range orderedKeys(r.Head, r.headOrder) {
+		a, err := entryAssertion(k, r.Head[k], env)
 		if err != nil {
-			return errors.Wrap(err, "extracting body param")
+			errs = append(errs, &AssertionError{Source: SourceHeader, Path: k, Err: errors.Wrap(err, "extracting header param")})
+			continue
 		}
-		actual, err := headerGetter.Get(path, resp.Header)
-		if err != nil {
-			return err
+
+		actual, typed, err := getHeader(r, resp, a.Path)
+		found := err == nil
+		if err != nil && !IsNoValue(err) {
+			errs = append(errs, &AssertionError{Source: SourceHeader, Path: a.Path, Op: a.Op, Expected: a.expectation(), Err: err})
+			continue
 		}
 
-		if err = equals(expected, actual); err != nil {
-			return errors.Wrap(err, "assertion failed")
+		if !typed {
+			a.stringOperands()
+		}
+		if aerr := a.evaluate(SourceHeader, actual, found); aerr != nil {
+			errs = append(errs, aerr)
+			continue
 		}
 
-		if set != "" {
-			env[set] = actual
+		if a.Set != "" && found {
+			env[a.Set] = actual
 		}
 	}
-	return nil
+	return errs.ErrOrNil()
 }
 
-func equals(exp string, act string) (err error) {
-	if exp != act {
-		return errors.Errorf("\n\texp: %v\n\tgot: %v", exp, act)
-	}
-	return
+func statusText(code int) string {
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
 }
@@ -276,3 +276,46 @@ func TestExtractParamYAMLSetter(t *testing.T) {
 	test.Equals(t, "content_type", set)
 	test.ErrorNil(t, err)
 }
+
+func TestProcessResponseCollectsAllFailures(t *testing.T) {
+	defer gock.Off()
+	gock.New("/").
+		Reply(500).
+		SetHeader("content-type", "application/json").
+		SetHeader("x-request-id", "abc").
+		BodyString(`{"hello":"world","id":"123"}`)
+
+	res, err := http.Get("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &RequestTest{
+		WantsCode: 200,
+		Head: map[string]interface{}{
+			"x-request-id": "xyz",
+		},
+		Body: map[string]interface{}{
+			"hello":   "there",
+			"missing": "value",
+			"id_key":  map[string]interface{}{"id": "123"},
+		},
+	}
+	env := map[string]interface{}{}
+
+	err = ProcessResponse(r, res, env)
+	errs, ok := err.(AssertionErrors)
+	if !ok {
+		t.Fatalf("expected AssertionErrors but got: %T", err)
+	}
+	test.Equals(t, 4, len(errs))
+
+	bySource := map[string]int{}
+	for _, e := range errs {
+		bySource[e.Source]++
+	}
+	test.Equals(t, map[string]int{SourceStatus: 1, SourceHeader: 1, SourceBody: 2}, bySource)
+
+	// The passing capture is still applied.
+	test.Equals(t, "123", env["id_key"])
+}
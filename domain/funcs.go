@@ -0,0 +1,174 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// templateRoot is the folder the file template function reads from.
+var templateRoot = "."
+
+// SetTemplateRoot sets the folder, usually the configuration folder,
+// that the file template function reads from.
+func SetTemplateRoot(dir string) {
+	templateRoot = dir
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// templateFuncs are the functions available to templates in test
+// files. They're listed in the README.
+var templateFuncs = template.FuncMap{
+	"uuid":       uuid,
+	"randString": randString,
+	"randInt":    randInt,
+
+	"now":         time.Now,
+	"formatTime":  func(layout string, t time.Time) string { return t.Format(layout) },
+	"addDuration": addDuration,
+	"unix":        func(t time.Time) int64 { return t.Unix() },
+
+	"base64Encode": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"base64Decode": func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	},
+	"urlEncode": url.QueryEscape,
+	"urlDecode": url.QueryUnescape,
+	"hexEncode": func(s string) string { return hex.EncodeToString([]byte(s)) },
+	"hexDecode": func(s string) (string, error) {
+		b, err := hex.DecodeString(s)
+		return string(b), err
+	},
+	"sha256": func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	},
+	"hmacSHA256": func(key, s string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	},
+	"toJSON": toJSON,
+
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+
+	"default":  defaultValue,
+	"required": required,
+
+	"env":  os.Getenv,
+	"file": readTemplateFile,
+}
+
+// uuid returns a random (version 4) UUID.
+func uuid() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// randString returns n random letters and digits.
+func randString(n int) (string, error) {
+	if n < 0 {
+		return "", errors.Errorf("randString: length (%d) must not be negative", n)
+	}
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphanumeric)))
+	for i := range b {
+		j, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphanumeric[j.Int64()]
+	}
+	return string(b), nil
+}
+
+// randInt returns a random integer in [min, max).
+func randInt(min, max int64) (int64, error) {
+	if max <= min {
+		return 0, errors.Errorf("randInt: max (%d) must be greater than min (%d)", max, min)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(max-min))
+	if err != nil {
+		return 0, err
+	}
+	return min + n.Int64(), nil
+}
+
+// addDuration adds a duration, such as "1h" or "-30m", to t.
+func addDuration(d string, t time.Time) (time.Time, error) {
+	v, err := time.ParseDuration(d)
+	if err != nil {
+		return t, err
+	}
+	return t.Add(v), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(normalizeNumbers(v))
+	return string(b), err
+}
+
+// defaultValue returns v, or def if v is missing or empty.
+func defaultValue(def, v interface{}) interface{} {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+// required returns v, or an error with msg if v is missing or empty.
+func required(msg string, v interface{}) (interface{}, error) {
+	if isEmpty(v) {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// readTemplateFile returns the contents of a file in the template
+// root. Paths leading outside of it are rejected.
+func readTemplateFile(path string) (string, error) {
+	full := filepath.Join(templateRoot, path)
+	rel, err := filepath.Rel(templateRoot, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("file %q is outside of %s", path, templateRoot)
+	}
+
+	b, err := ioutil.ReadFile(full)
+	return string(b), err
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "litmus")
+	test.ErrorNil(t, err)
+	defer os.RemoveAll(dir)
+	test.ErrorNil(t, ioutil.WriteFile(filepath.Join(dir, "body.json"), []byte(`{"a":1}`), 0644))
+
+	SetTemplateRoot(dir)
+	defer SetTemplateRoot(".")
+	os.Setenv("LITMUS_FUNCS_TEST", "from os")
+	defer os.Unsetenv("LITMUS_FUNCS_TEST")
+
+	env := map[string]interface{}{
+		"name":  " Bob ",
+		"empty": "",
+		"list":  []interface{}{float64(1), "two"},
+	}
+
+	tests := []struct {
+		name string
+		tpl  string
+		exp  string
+		err  bool
+	}{
+		{name: "upper", tpl: `{{upper "abc"}}`, exp: "ABC"},
+		{name: "lower", tpl: `{{lower "ABC"}}`, exp: "abc"},
+		{name: "trim", tpl: `{{trim .name}}`, exp: "Bob"},
+		{name: "base64", tpl: `{{base64Encode "user:pass"}}`, exp: "dXNlcjpwYXNz"},
+		{name: "base64 decode", tpl: `{{base64Decode "dXNlcjpwYXNz"}}`, exp: "user:pass"},
+		{name: "url", tpl: `{{urlEncode "a b&c"}}`, exp: "a+b%26c"},
+		{name: "url decode", tpl: `{{urlDecode "a+b%26c"}}`, exp: "a b&c"},
+		{name: "hex", tpl: `{{hexEncode "hi"}}`, exp: "6869"},
+		{name: "hex decode", tpl: `{{hexDecode "6869"}}`, exp: "hi"},
+		{name: "sha256", tpl: `{{sha256 "abc"}}`, exp: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{name: "hmac", tpl: `{{hmacSHA256 "key" "The quick brown fox jumps over the lazy dog"}}`, exp: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{name: "json", tpl: `{{toJSON .list}}`, exp: `[1,"two"]`},
+		{name: "default missing", tpl: `{{.missing | default "x"}}`, exp: "x"},
+		{name: "default empty", tpl: `{{.empty | default "x"}}`, exp: "x"},
+		{name: "default set", tpl: `{{.name | default "x"}}`, exp: " Bob "},
+		{name: "required", tpl: `{{.missing | required "missing is required"}}`, err: true},
+		{name: "env", tpl: `{{env "LITMUS_FUNCS_TEST"}}`, exp: "from os"},
+		{name: "file", tpl: `{{file "body.json"}}`, exp: `{"a":1}`},
+		{name: "file outside root", tpl: `{{file "../secret"}}`, err: true},
+		{name: "time", tpl: `{{formatTime "2006" (addDuration "8760h" now) | len}}`, exp: "4"},
+		{name: "bad duration", tpl: `{{addDuration "soon" now}}`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := applyTpl(tt.tpl, env)
+			if tt.err {
+				test.Assert(t, err != nil)
+				return
+			}
+			test.ErrorNil(t, err)
+			test.Equals(t, tt.exp, out)
+		})
+	}
+}
+
+func TestTemplateRandomFuncs(t *testing.T) {
+	out, err := applyTpl(`{{uuid}}`, nil)
+	test.ErrorNil(t, err)
+	test.Assert(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(out))
+
+	out, err = applyTpl(`{{randString 12}}`, nil)
+	test.ErrorNil(t, err)
+	test.Assert(t, regexp.MustCompile(`^[a-zA-Z0-9]{12}$`).MatchString(out))
+
+	other, err := applyTpl(`{{randString 12}}`, nil)
+	test.ErrorNil(t, err)
+	test.Assert(t, out != other)
+
+	_, err = applyTpl(`{{randString -1}}`, nil)
+	test.Assert(t, err != nil)
+
+	out, err = applyTpl(`{{randInt 5 10}}`, nil)
+	test.ErrorNil(t, err)
+	n, err := strconv.Atoi(out)
+	test.ErrorNil(t, err)
+	test.Assert(t, n >= 5 && n < 10)
+
+	_, err = applyTpl(`{{randInt 5 5}}`, nil)
+	test.Assert(t, err != nil)
+
+	out, err = applyTpl(`{{unix now}}`, nil)
+	test.ErrorNil(t, err)
+	_, err = strconv.ParseInt(out, 10, 64)
+	test.ErrorNil(t, err)
+}
+
+func TestStrictTemplates(t *testing.T) {
+	_, err := applyTpl("{{.base_servce_url}}/get", map[string]interface{}{"base_service_url": "localhost"})
+	test.Assert(t, err != nil)
+
+	SetStrictTemplates(false)
+	defer SetStrictTemplates(true)
+
+	out, err := applyTpl("{{.base_servce_url}}/get", map[string]interface{}{})
+	test.ErrorNil(t, err)
+	test.Equals(t, "<no value>/get", out)
+}
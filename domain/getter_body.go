@@ -1,42 +1,92 @@
 package domain
 
 import (
+	"mime"
 	"net/http"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 )
 
+// Body types that can be used to force a particular BodyGetter,
+// regardless of the Content-Type returned by the server.
+const (
+	BodyTypeJSON = "json"
+	BodyTypeXML  = "xml"
+	BodyTypeHTML = "html"
+	BodyTypeText = "text"
+)
+
 // BodyGetter defines the behavior or something that can
 // extract information from a response body.
 type BodyGetter interface {
-	Get(path string, body []byte) (value string, err error)
+	Get(path string, body []byte) (value interface{}, err error)
 }
 
 // NewBodyGetter returns the body extracter based on the
-// Content-Type found in the response headers.
+// Content-Type found in the response headers. Parameters
+// such as charset are ignored and structured syntax
+// suffixes (+json, +xml) are honoured.
 func NewBodyGetter(resp *http.Response) (e BodyGetter, err error) {
 	contentType := resp.Header.Get("Content-Type")
 
-	switch contentType {
-	case "application/json":
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, errors.Errorf("invalid Content-Type %q", contentType)
+	}
+
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
 		return &JSONBodyGetter{}, nil
+	case mediaType == "text/html":
+		return &HTMLBodyGetter{}, nil
+	case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+		return &XMLBodyGetter{}, nil
+	case strings.HasPrefix(mediaType, "text/"):
+		return &TextBodyGetter{}, nil
 	default:
 		return nil, errors.Errorf("invalid Content-Type %q", contentType)
 	}
 }
 
+// NewBodyGetterForType returns the body extracter for one
+// of the BodyType constants.
+func NewBodyGetterForType(bodyType string) (e BodyGetter, err error) {
+	switch strings.ToLower(bodyType) {
+	case BodyTypeJSON:
+		return &JSONBodyGetter{}, nil
+	case BodyTypeXML:
+		return &XMLBodyGetter{}, nil
+	case BodyTypeHTML:
+		return &HTMLBodyGetter{}, nil
+	case BodyTypeText:
+		return &TextBodyGetter{}, nil
+	default:
+		return nil, errors.Errorf("invalid body type %q", bodyType)
+	}
+}
+
 // JSONBodyGetter extracts information from a response
 // body using JSON dot notation.
 type JSONBodyGetter struct{}
 
 // Get extracts a value out of a JSON body using JSON
-// dot notation.
-func (e *JSONBodyGetter) Get(path string, body []byte) (value string, err error) {
+// dot notation. The value keeps its JSON type, with whole
+// numbers returned as int64.
+func (e *JSONBodyGetter) Get(path string, body []byte) (value interface{}, err error) {
 	result := gjson.GetBytes(body, path)
 	if !result.Exists() {
-		return "", errors.Errorf("no value at path %q in JSON body", path)
+		return nil, &NoValueError{Path: path, Source: "JSON body"}
 	}
 
-	return result.String(), nil
+	return normalizeNumbers(result.Value()), nil
+}
+
+// isTyped returns true if the getter returns values in their
+// native type. Values from other getters are always strings,
+// so expectations are compared against them as strings.
+func isTyped(g BodyGetter) bool {
+	_, ok := g.(*JSONBodyGetter)
+	return ok
 }
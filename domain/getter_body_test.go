@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestNewBodyGetter(t *testing.T) {
+	tests := []struct {
+		contentType string
+		exp         BodyGetter
+		wantErr     bool
+	}{
+		{contentType: "application/json", exp: &JSONBodyGetter{}},
+		{contentType: "application/json; charset=utf-8", exp: &JSONBodyGetter{}},
+		{contentType: "application/problem+json", exp: &JSONBodyGetter{}},
+		{contentType: "application/xml", exp: &XMLBodyGetter{}},
+		{contentType: "text/xml; charset=utf-8", exp: &XMLBodyGetter{}},
+		{contentType: "application/atom+xml", exp: &XMLBodyGetter{}},
+		{contentType: "text/html; charset=utf-8", exp: &HTMLBodyGetter{}},
+		{contentType: "text/plain", exp: &TextBodyGetter{}},
+		{contentType: "application/octet-stream", wantErr: true},
+		{contentType: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{"Content-Type": {tt.contentType}}}
+			act, err := NewBodyGetter(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewBodyGetter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
+
+func TestXMLBodyGetter(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<order id="42">
+	<item sku="a">apple</item>
+	<item sku="b">banana</item>
+</order>`)
+
+	tests := []struct {
+		path    string
+		exp     interface{}
+		wantErr bool
+	}{
+		{path: "/order/@id", exp: "42"},
+		{path: "//item[2]", exp: "banana"},
+		{path: "//item[@sku='a']", exp: "apple"},
+		{path: "count(//item)", exp: "2"},
+		{path: "//missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			act, err := (&XMLBodyGetter{}).Get(tt.path, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
+
+func TestHTMLBodyGetter(t *testing.T) {
+	body := []byte(`<html><body>
+	<h1 class="title"> Hello </h1>
+	<a class="next" href="/page/2">next</a>
+	<a href="mailto:help@example.com" title="Email">help</a>
+</body></html>`)
+
+	tests := []struct {
+		path    string
+		exp     interface{}
+		wantErr bool
+	}{
+		{path: "h1.title", exp: "Hello"},
+		{path: "a.next@href", exp: "/page/2"},
+		{path: "a.next@rel", wantErr: true},
+		{path: `a[href^="mailto:help@"]`, exp: "help"},
+		{path: `a[href^="mailto:help@"]@title`, exp: "Email"},
+		{path: `a[href$='@example.com']@href`, exp: "mailto:help@example.com"},
+		{path: "table", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			act, err := (&HTMLBodyGetter{}).Get(tt.path, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
+
+func TestTextBodyGetter(t *testing.T) {
+	body := []byte("status: ok\nversion: 1.2.3")
+
+	tests := []struct {
+		path    string
+		exp     interface{}
+		wantErr bool
+	}{
+		{path: "", exp: string(body)},
+		{path: ".", exp: string(body)},
+		{path: `version: (\S+)`, exp: "1.2.3"},
+		{path: `status: \w+`, exp: "status: ok"},
+		{path: `missing`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			act, err := (&TextBodyGetter{}).Get(tt.path, body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
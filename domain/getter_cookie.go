@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cookieAttributes are the attributes of a cookie that can be
+// selected by suffixing the cookie's name, as in session.secure.
+var cookieAttributes = map[string]bool{
+	"value":     true,
+	"path":      true,
+	"domain":    true,
+	"expires":   true,
+	"max_age":   true,
+	"secure":    true,
+	"http_only": true,
+	"same_site": true,
+}
+
+// CookieGetter extracts information from the cookies set by
+// a response.
+type CookieGetter struct{}
+
+// Get returns the value of the named cookie. The name may be
+// followed by one of the cookie's attributes, such as
+// session.secure or session.same_site. Expires is returned in
+// RFC 3339 format. Only a missing cookie has no value: the value of
+// a cookie set to an empty string, as clearing cookies often are,
+// and the attributes a cookie doesn't set, are empty.
+func (e *CookieGetter) Get(path string, resp *http.Response) (value interface{}, err error) {
+	name, attr := path, "value"
+	if i := strings.LastIndex(path, "."); i != -1 && cookieAttributes[strings.ToLower(path[i+1:])] {
+		name, attr = path[:i], strings.ToLower(path[i+1:])
+	}
+
+	var cookie *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == name {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		return nil, &NoValueError{Path: path, Source: "cookies"}
+	}
+
+	switch attr {
+	case "path":
+		value = cookie.Path
+	case "domain":
+		value = cookie.Domain
+	case "expires":
+		value = ""
+		if !cookie.Expires.IsZero() {
+			value = cookie.Expires.UTC().Format(time.RFC3339)
+		}
+	case "max_age":
+		value = int64(cookie.MaxAge)
+	case "secure":
+		value = cookie.Secure
+	case "http_only":
+		value = cookie.HttpOnly
+	case "same_site":
+		value = sameSite(cookie.SameSite)
+	default:
+		value = cookie.Value
+	}
+	return value, nil
+}
+
+func sameSite(s http.SameSite) string {
+	switch s {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
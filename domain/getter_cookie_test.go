@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestCookieGetter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Set-Cookie": {
+		"session=abc123; Path=/; Expires=Wed, 21 Oct 2026 07:28:00 GMT; Secure; HttpOnly; SameSite=Strict",
+		"theme=dark; Max-Age=60",
+		"cleared=; Path=/; Max-Age=0",
+	}}}
+
+	tests := []struct {
+		path    string
+		exp     interface{}
+		wantErr bool
+	}{
+		{path: "session", exp: "abc123"},
+		{path: "session.value", exp: "abc123"},
+		{path: "session.path", exp: "/"},
+		{path: "session.expires", exp: "2026-10-21T07:28:00Z"},
+		{path: "session.secure", exp: true},
+		{path: "session.http_only", exp: true},
+		{path: "session.same_site", exp: "Strict"},
+		{path: "theme.secure", exp: false},
+		{path: "theme.max_age", exp: int64(60)},
+		{path: "theme.same_site", exp: ""},
+		{path: "theme.domain", exp: ""},
+		{path: "theme.expires", exp: ""},
+		{path: "cleared", exp: ""},
+		{path: "cleared.path", exp: "/"},
+		{path: "missing", wantErr: true},
+		{path: "missing.secure", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			act, err := (&CookieGetter{}).Get(tt.path, resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
+
+func TestCookieGetterConfig(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Set-Cookie": {
+		"session=abc123; Secure; HttpOnly",
+	}}}
+
+	r := &RequestTest{
+		Getters: GetterConfigs{
+			{Type: "cookie", Path: "session", Set: "session_id"},
+			{Type: "cookie", Path: "session.secure", Expected: true},
+			{Type: "cookie", Path: "session.same_site", Expected: ""},
+			{Type: "cookie", Path: "missing", Op: "absent"},
+		},
+	}
+	env := map[string]interface{}{}
+
+	test.ErrorNil(t, Getters(r, resp, env))
+	test.Equals(t, "abc123", env["session_id"])
+}
@@ -2,20 +2,79 @@ package domain
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
-	"strings"
 )
 
+// headerPath matches a header name followed by a selector,
+// such as Set-Cookie[1].
+var headerPath = regexp.MustCompile(`^(.+?)\[(.*)\]$`)
+
 // HeaderGetter extracts information from response headers.
 type HeaderGetter struct{}
 
-// Get extracts a value out of request headers.
-func (e *HeaderGetter) Get(path string, header http.Header) (value string, err error) {
+// Get extracts a value out of request headers. Header names are
+// matched case-insensitively and may be followed by a selector
+// to choose between multiple values of the same header:
+//
+//	Name        the first value
+//	Name[1]     the value at the index, negative indexes count from the end
+//	Name[*]     all of the values, as a list
+//	Name[#]     the number of values
+//	Name[join]  all of the values, joined by ", "
+func (e *HeaderGetter) Get(path string, header http.Header) (value interface{}, err error) {
+	name, selector := path, ""
+	if m := headerPath.FindStringSubmatch(path); m != nil {
+		name, selector = m[1], m[2]
+	}
+
+	values := headerValues(name, header)
+	if selector == "#" {
+		return int64(len(values)), nil
+	}
+	if len(values) == 0 {
+		return nil, &NoValueError{Path: path, Source: "headers"}
+	}
+
+	switch selector {
+	case "":
+		return values[0], nil
+	case "*":
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		return list, nil
+	case "join":
+		return strings.Join(values, ", "), nil
+	}
+
+	i, err := strconv.Atoi(selector)
+	if err != nil {
+		return nil, errors.Errorf("invalid header selector %q", selector)
+	}
+	if i < 0 {
+		i += len(values)
+	}
+	if i < 0 || i >= len(values) {
+		return nil, &NoValueError{Path: path, Source: "headers"}
+	}
+	return values[i], nil
+}
+
+// headerValues returns the values of the header, matching its
+// name case-insensitively.
+func headerValues(name string, header http.Header) []string {
+	if v, ok := header[http.CanonicalHeaderKey(name)]; ok {
+		return v
+	}
 	for k, v := range header {
-		if strings.ToLower(path) == strings.ToLower(k) && len(v) > 0 {
-			return v[0], nil
+		if strings.EqualFold(name, k) {
+			return v
 		}
 	}
-	return "", errors.New("no matching header found")
+	return nil
 }
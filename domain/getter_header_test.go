@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestHeaderGetter(t *testing.T) {
+	header := http.Header{
+		"Set-Cookie":   {"a=1", "b=2", "c=3"},
+		"Content-Type": {"application/json"},
+		"x-lower":      {"lower"},
+	}
+
+	tests := []struct {
+		path    string
+		exp     interface{}
+		wantErr bool
+	}{
+		{path: "content-type", exp: "application/json"},
+		{path: "X-Lower", exp: "lower"},
+		{path: "Set-Cookie", exp: "a=1"},
+		{path: "Set-Cookie[1]", exp: "b=2"},
+		{path: "Set-Cookie[-1]", exp: "c=3"},
+		{path: "Set-Cookie[*]", exp: []interface{}{"a=1", "b=2", "c=3"}},
+		{path: "Set-Cookie[#]", exp: int64(3)},
+		{path: "Set-Cookie[join]", exp: "a=1, b=2, c=3"},
+		{path: "X-Missing[#]", exp: int64(0)},
+		{path: "Set-Cookie[3]", wantErr: true},
+		{path: "Set-Cookie[first]", wantErr: true},
+		{path: "X-Missing", wantErr: true},
+		{path: "X-Missing[*]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			act, err := (&HeaderGetter{}).Get(tt.path, header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			test.Equals(t, tt.exp, act)
+		})
+	}
+}
+
+func TestHeaderMultipleValues(t *testing.T) {
+	// gock only keeps the first value of each header.
+	res := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Vary": {"Accept", "Origin"}},
+	}
+
+	r := &RequestTest{
+		Head: map[string]interface{}{
+			"Vary[*]":   map[string]interface{}{"op": "contains", "value": "Origin"},
+			"Vary[#]":   2,
+			"Vary[1]":   "Origin",
+			"X-Debug":   map[string]interface{}{"op": "absent"},
+			"vary_list": map[string]interface{}{"Vary[join]": "Accept, Origin"},
+		},
+	}
+	env := map[string]interface{}{}
+
+	test.ErrorNil(t, Header(r, res, env))
+	test.Equals(t, "Accept, Origin", env["vary_list"])
+}
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// HTMLBodyGetter extracts information from an HTML response
+// body using CSS selectors.
+type HTMLBodyGetter struct{}
+
+// Get returns the text of the first element matching the CSS
+// selector in path. If the path ends in @name, the value of
+// the element's name attribute is returned instead, for
+// example "a.next@href".
+func (e *HTMLBodyGetter) Get(path string, body []byte) (value interface{}, err error) {
+	selector, attr := splitSelectorAttr(path)
+
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compiling selector %q", selector)
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing HTML body")
+	}
+
+	node := sel.MatchFirst(doc)
+	if node == nil {
+		return nil, &NoValueError{Path: path, Source: "HTML body"}
+	}
+
+	if attr == "" {
+		return strings.TrimSpace(htmlText(node)), nil
+	}
+	for _, a := range node.Attr {
+		if a.Key == attr {
+			return a.Val, nil
+		}
+	}
+	return nil, &NoValueError{Path: path, Source: "HTML body"}
+}
+
+// splitSelectorAttr splits a path into its CSS selector and the
+// attribute named after its last @. An @ inside brackets or quotes,
+// as in a[href^="mailto:x@y"], is part of the selector.
+func splitSelectorAttr(path string) (selector, attr string) {
+	split := -1
+	depth := 0
+	var quote byte
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']' && depth > 0:
+			depth--
+		case c == '@' && depth == 0:
+			split = i
+		}
+	}
+	if split == -1 {
+		return path, ""
+	}
+	return path[:split], path[split+1:]
+}
+
+func htmlText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	buf := &bytes.Buffer{}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		buf.WriteString(htmlText(c))
+	}
+	return buf.String()
+}
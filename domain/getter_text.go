@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// TextBodyGetter extracts information from a plain text
+// response body.
+type TextBodyGetter struct{}
+
+// Get returns the whole body when the path is empty or ".",
+// otherwise the path is treated as a regular expression. If
+// the expression contains a capture group, the first group
+// is returned, otherwise the whole match is returned.
+func (e *TextBodyGetter) Get(path string, body []byte) (value interface{}, err error) {
+	if path == "" || path == "." {
+		return string(body), nil
+	}
+
+	re, err := regexp.Compile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compiling expression %q", path)
+	}
+
+	match := re.FindSubmatch(body)
+	if match == nil {
+		return nil, &NoValueError{Path: path, Source: "text body"}
+	}
+	if len(match) > 1 {
+		return string(match[1]), nil
+	}
+	return string(match[0]), nil
+}
@@ -0,0 +1,216 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/antchfx/xpath"
+	"github.com/pkg/errors"
+)
+
+// XMLBodyGetter extracts information from an XML response
+// body using XPath expressions.
+type XMLBodyGetter struct{}
+
+// Get evaluates the XPath expression in path against the body.
+// Node sets return the text of the first node, while functions
+// such as count() return their result.
+func (e *XMLBodyGetter) Get(path string, body []byte) (value interface{}, err error) {
+	expr, err := xpath.Compile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "compiling expression %q", path)
+	}
+
+	root, err := parseXML(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing XML body")
+	}
+
+	switch x := expr.Evaluate(&xmlNavigator{root: root, cur: root, attr: -1}).(type) {
+	case *xpath.NodeIterator:
+		if !x.MoveNext() {
+			return nil, &NoValueError{Path: path, Source: "XML body"}
+		}
+		return x.Current().Value(), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case string:
+		return x, nil
+	default:
+		return nil, errors.Errorf("unexpected result %T at path %q in XML body", x, path)
+	}
+}
+
+// xmlNode is a node in a parsed XML document.
+type xmlNode struct {
+	typ   xpath.NodeType
+	name  xml.Name
+	data  string
+	attrs []xml.Attr
+
+	parent, firstChild, lastChild, prev, next *xmlNode
+}
+
+func (n *xmlNode) appendChild(c *xmlNode) {
+	c.parent = n
+	if n.lastChild == nil {
+		n.firstChild = c
+	} else {
+		n.lastChild.next = c
+		c.prev = n.lastChild
+	}
+	n.lastChild = c
+}
+
+func (n *xmlNode) text() string {
+	switch n.typ {
+	case xpath.TextNode, xpath.CommentNode:
+		return n.data
+	}
+
+	buf := &bytes.Buffer{}
+	for c := n.firstChild; c != nil; c = c.next {
+		if c.typ != xpath.CommentNode {
+			buf.WriteString(c.text())
+		}
+	}
+	return buf.String()
+}
+
+func parseXML(body []byte) (*xmlNode, error) {
+	root := &xmlNode{typ: xpath.RootNode}
+	cur := root
+
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{typ: xpath.ElementNode, name: t.Name, attrs: t.Attr}
+			cur.appendChild(n)
+			cur = n
+		case xml.EndElement:
+			cur = cur.parent
+		case xml.CharData:
+			cur.appendChild(&xmlNode{typ: xpath.TextNode, data: string(t)})
+		case xml.Comment:
+			cur.appendChild(&xmlNode{typ: xpath.CommentNode, data: string(t)})
+		}
+	}
+}
+
+// xmlNavigator implements xpath.NodeNavigator over an xmlNode
+// tree. An attr of -1 means the navigator is positioned on the
+// node itself rather than one of its attributes.
+type xmlNavigator struct {
+	root, cur *xmlNode
+	attr      int
+}
+
+func (x *xmlNavigator) NodeType() xpath.NodeType {
+	if x.attr != -1 {
+		return xpath.AttributeNode
+	}
+	return x.cur.typ
+}
+
+func (x *xmlNavigator) LocalName() string {
+	if x.attr != -1 {
+		return x.cur.attrs[x.attr].Name.Local
+	}
+	return x.cur.name.Local
+}
+
+func (x *xmlNavigator) Prefix() string {
+	return ""
+}
+
+func (x *xmlNavigator) Value() string {
+	if x.attr != -1 {
+		return x.cur.attrs[x.attr].Value
+	}
+	return x.cur.text()
+}
+
+func (x *xmlNavigator) Copy() xpath.NodeNavigator {
+	n := *x
+	return &n
+}
+
+func (x *xmlNavigator) MoveToRoot() {
+	x.cur, x.attr = x.root, -1
+}
+
+func (x *xmlNavigator) MoveToParent() bool {
+	if x.attr != -1 {
+		x.attr = -1
+		return true
+	}
+	if x.cur.parent == nil {
+		return false
+	}
+	x.cur = x.cur.parent
+	return true
+}
+
+func (x *xmlNavigator) MoveToNextAttribute() bool {
+	if x.attr >= len(x.cur.attrs)-1 {
+		return false
+	}
+	x.attr++
+	return true
+}
+
+func (x *xmlNavigator) MoveToChild() bool {
+	if x.attr != -1 || x.cur.firstChild == nil {
+		return false
+	}
+	x.cur = x.cur.firstChild
+	return true
+}
+
+func (x *xmlNavigator) MoveToFirst() bool {
+	if x.attr != -1 || x.cur.prev == nil {
+		return false
+	}
+	for x.cur.prev != nil {
+		x.cur = x.cur.prev
+	}
+	return true
+}
+
+func (x *xmlNavigator) MoveToNext() bool {
+	if x.attr != -1 || x.cur.next == nil {
+		return false
+	}
+	x.cur = x.cur.next
+	return true
+}
+
+func (x *xmlNavigator) MoveToPrevious() bool {
+	if x.attr != -1 || x.cur.prev == nil {
+		return false
+	}
+	x.cur = x.cur.prev
+	return true
+}
+
+func (x *xmlNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	o, ok := other.(*xmlNavigator)
+	if !ok || o.root != x.root {
+		return false
+	}
+	x.cur, x.attr = o.cur, o.attr
+	return true
+}
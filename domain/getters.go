@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// getter extracts the value at path from a response. The typed
+// result reports whether the value keeps its native type, or is
+// always a string.
+type getter func(r *RequestTest, resp *http.Response, path string) (value interface{}, typed bool, err error)
+
+// getters is the registry of getters that can be referenced by
+// the type of a GetterConfig.
+var getters = map[string]getter{
+	SourceStatus: getStatus,
+	SourceHeader: getHeader,
+	SourceBody:   getBody,
+	SourceCookie: getCookie,
+}
+
+// Getters - runs the getters of a test in the order they were declared,
+// checking and capturing the values they extract.
+func Getters(r *RequestTest, resp *http.Response, env map[string]interface{}) error {
+	if resp == nil {
+		return errors.New("unexpected nil response")
+	}
+
+	var errs AssertionErrors
+	for _, gc := range r.Getters {
+		source := strings.ToLower(gc.Type)
+
+		// gc is a copy, so the test's own config is left
+		// untouched by the environment.
+		if err := gc.applyEnv(env); err != nil {
+			errs = append(errs, &AssertionError{Source: source, Path: gc.Path, Err: errors.Wrap(err, "applying environment")})
+			continue
+		}
+
+		a, err := gc.assertion()
+		if err != nil {
+			errs = append(errs, &AssertionError{Source: source, Path: gc.Path, Err: errors.Wrap(err, "extracting getter")})
+			continue
+		}
+
+		get, ok := getters[source]
+		if !ok {
+			errs = append(errs, &AssertionError{Source: source, Path: gc.Path, Err: errors.Errorf("unknown getter type %q", gc.Type)})
+			continue
+		}
+
+		actual, typed, err := get(r, resp, a.Path)
+		found := err == nil
+		if err != nil && !IsNoValue(err) {
+			errs = append(errs, &AssertionError{Source: source, Path: a.Path, Op: a.Op, Expected: a.expectation(), Err: err})
+			continue
+		}
+
+		if !typed {
+			a.stringOperands()
+		}
+		if aerr := a.evaluate(source, actual, found); aerr != nil {
+			errs = append(errs, aerr)
+			continue
+		}
+
+		if a.Set != "" && found {
+			env[a.Set] = actual
+		}
+	}
+	return errs.ErrOrNil()
+}
+
+// assertion converts the config into an Assertion. Without an
+// op or an expected value, the getter only asserts that the
+// value exists before capturing it.
+func (gc *GetterConfig) assertion() (a Assertion, err error) {
+	a = Assertion{Path: gc.Path, Op: strings.ToLower(gc.Op), Value: gc.Expected, Set: gc.Set}
+	if a.Op == "" {
+		a.Op = OpEquals
+		if a.Value == nil {
+			a.Op = OpExists
+		}
+	}
+	return a, a.validate()
+}
+
+func getStatus(_ *RequestTest, resp *http.Response, _ string) (interface{}, bool, error) {
+	return int64(resp.StatusCode), true, nil
+}
+
+// getHeader returns header values, which are only typed when a
+// selector returns a list or count rather than a single value.
+func getHeader(_ *RequestTest, resp *http.Response, path string) (interface{}, bool, error) {
+	value, err := (&HeaderGetter{}).Get(path, resp.Header)
+	if err != nil {
+		return nil, false, err
+	}
+	_, isString := value.(string)
+	return value, !isString, nil
+}
+
+// getCookie returns cookie values, which are only typed for
+// attributes that aren't strings, such as secure.
+func getCookie(_ *RequestTest, resp *http.Response, path string) (interface{}, bool, error) {
+	value, err := (&CookieGetter{}).Get(path, resp)
+	if err != nil {
+		return nil, false, err
+	}
+	_, isString := value.(string)
+	return value, !isString, nil
+}
+
+func getBody(r *RequestTest, resp *http.Response, path string) (interface{}, bool, error) {
+	bodyGetter, err := newBodyGetter(r, resp)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "creating body getter")
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "reading response body")
+	}
+
+	value, err := bodyGetter.Get(path, body)
+	return value, isTyped(bodyGetter), err
+}
+
+// readBody reads the response body, replacing it with a copy
+// so it can be read again by other extractors.
+func readBody(resp *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/LUSHDigital/litmus/test"
+	"github.com/h2non/gock"
+	"gopkg.in/yaml.v2"
+)
+
+var tomlGetters = `
+[litmus]
+[[litmus.test]]
+name="getters"
+[litmus.test.body]
+"headers.Connection" = "close"
+[[litmus.test.getters]]
+type="status"
+exp=200
+[[litmus.test.getters]]
+type="header"
+path="Content-Type"
+op="starts_with"
+exp="application/json"
+[[litmus.test.getters]]
+type="body"
+path="headers.Connection"
+exp="close"
+set="some_key"
+[[litmus.test.getters]]
+type="body"
+path="headers.Count"
+set="count"`
+
+var yamlGetters = `
+litmus:
+  test:
+  - name: getters
+    body:
+      headers.Connection: close
+    getters:
+    - type: status
+      exp: 200
+    - type: header
+      path: Content-Type
+      op: starts_with
+      exp: application/json
+    - type: body
+      path: headers.Connection
+      exp: close
+      set: some_key
+    - type: body
+      path: headers.Count
+      set: count`
+
+func TestGetters(t *testing.T) {
+	var tomlFile, yamlFile TestFile
+	if err := toml.Unmarshal([]byte(tomlGetters), &tomlFile); err != nil {
+		t.Fatalf("error unmarshalling test file: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(yamlGetters), &yamlFile); err != nil {
+		t.Fatalf("error unmarshalling test file: %v", err)
+	}
+
+	for name, tf := range map[string]TestFile{"toml": tomlFile, "yaml": yamlFile} {
+		t.Run(name, func(t *testing.T) {
+			defer gock.Off()
+			gock.New("/").
+				Reply(200).
+				SetHeader("content-type", "application/json; charset=utf-8").
+				BodyString(`{"headers":{"Connection":"close","Count":3}}`)
+
+			res, err := http.Get("/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := tf.Litmus.Test[0]
+			env := map[string]interface{}{}
+			if err := r.ApplyEnv(env); err != nil {
+				t.Fatal(err)
+			}
+
+			test.ErrorNil(t, ProcessResponse(&r, res, env))
+			test.Equals(t, "close", env["some_key"])
+			test.Equals(t, int64(3), env["count"])
+		})
+	}
+}
+
+func TestGettersFailures(t *testing.T) {
+	defer gock.Off()
+	gock.New("/").
+		Reply(404).
+		SetHeader("content-type", "application/json").
+		BodyString(`{}`)
+
+	res, err := http.Get("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &RequestTest{
+		Getters: GetterConfigs{
+			{Type: "status", Expected: int64(200)},
+			{Type: "body", Path: "missing", Set: "missing"},
+			{Type: "carrier-pigeon", Path: "anything"},
+			{Type: "header", Path: "Content-Type", Op: "approx", Expected: "json"},
+		},
+	}
+	env := map[string]interface{}{}
+
+	err = Getters(r, res, env)
+	errs, ok := err.(AssertionErrors)
+	if !ok {
+		t.Fatalf("expected AssertionErrors but got: %T", err)
+	}
+	test.Equals(t, 4, len(errs))
+	test.Equals(t, SourceStatus, errs[0].Source)
+	test.Equals(t, SourceBody, errs[1].Source)
+	_, set := env["missing"]
+	test.Assert(t, !set)
+}
@@ -0,0 +1,293 @@
+package domain
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// LintError is a problem found in a test file. Line is zero when
+// the problem's position isn't known.
+type LintError struct {
+	Path string
+	Line int
+	Test string
+	Msg  string
+}
+
+func (e LintError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+		}
+		b.WriteString(": ")
+	}
+	if e.Test != "" {
+		fmt.Fprintf(&b, "%q: ", e.Test)
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// LintErrors collects the problems found in one or more test files.
+type LintErrors []LintError
+
+func (e LintErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ErrOrNil returns nil if there are no problems.
+func (e LintErrors) ErrOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	tomlLineRe = regexp.MustCompile(`^Near line (\d+) \(last key parsed '[^']*'\): (.*)$`)
+	yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+)
+
+// UnmarshalTOMLStrict is like UnmarshalTOML, but also rejects keys
+// that don't correspond to a field.
+func UnmarshalTOMLStrict(data []byte, tf *TestFile) error {
+	md, err := toml.Decode(string(data), tf)
+	if err != nil {
+		if m := tomlLineRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return LintErrors{{Path: tf.Path, Line: line, Msg: m[2]}}
+		}
+		return LintErrors{{Path: tf.Path, Msg: err.Error()}}
+	}
+	recordTOMLOrder(md, tf)
+
+	undecoded := make(map[string]bool)
+	for _, key := range md.Undecoded() {
+		undecoded[key.String()] = true
+	}
+
+	var errs LintErrors
+	index := make(map[string]int)
+	problem := func(key toml.Key, msg string) {
+		err := LintError{Path: tf.Path, Msg: msg}
+		i := -1
+		switch {
+		case len(key) > 2 && key[0] == "litmus" && key[1] == sectionTemplates:
+			err.Test = key[2]
+		case len(key) > 2 && key[0] == "litmus":
+			i = index[key[1]] - 1
+			if requests := tf.Litmus.Section(key[1]); i >= 0 && i < len(requests) {
+				err.Test = requests[i].Name
+			}
+		}
+		err.Line = locateTOMLKey(data, key, i)
+		errs = append(errs, err)
+	}
+
+	for _, key := range md.Keys() {
+		if len(key) == 2 && key[0] == "litmus" {
+			index[key[1]]++
+		}
+		// Templates are keyed by name, so their fields are a
+		// level deeper than those of other requests.
+		field := 2
+		if len(key) > 1 && key[1] == sectionTemplates {
+			field = 3
+		}
+		if len(key) > field && key[0] == "litmus" && tableFields[key[field]] {
+			// The contents of maps aren't checked, as they hold
+			// arbitrary values.
+			if typ := md.Type(key[:field+1]...); len(key) == field+1 && typ != "Hash" {
+				problem(key, fmt.Sprintf("%s must be a table, got %s", key[field], strings.ToLower(typ)))
+			}
+			continue
+		}
+		if undecoded[key.String()] && !undecoded[key[:len(key)-1].String()] {
+			problem(key, fmt.Sprintf("unknown field %q", key[len(key)-1]))
+		}
+	}
+	return errs.ErrOrNil()
+}
+
+// tableFields are the keys of a request that decode into maps. The
+// TOML decoder silently ignores other values for them.
+var tableFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(RequestTest{})
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Type.Kind() == reflect.Map {
+			fields[strings.Split(f.Tag.Get("toml"), ",")[0]] = true
+		}
+	}
+	return fields
+}()
+
+var (
+	tomlHeaderRe = regexp.MustCompile(`^\s*\[\[?\s*([^\]]+?)\s*\]\]?`)
+	tomlKeyRe    = regexp.MustCompile(`^\s*(?:"([^"]+)"|'([^']+)'|([A-Za-z0-9_-]+))\s*=`)
+)
+
+// locateTOMLKey returns the line on which key is defined, or zero if
+// it can't be found. index is the position of the request the key
+// belongs to within its section, or -1 if it doesn't belong to one.
+// It's a best effort: keys in inline tables aren't found.
+func locateTOMLKey(data []byte, key toml.Key, index int) int {
+	parent := key[:len(key)-1].String()
+	name := key[len(key)-1]
+
+	table := ""
+	count := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if m := tomlHeaderRe.FindStringSubmatch(text); m != nil && !strings.Contains(text, "=") {
+			table = strings.Replace(m[1], " ", "", -1)
+			parts := strings.Split(table, ".")
+			if strings.HasPrefix(strings.TrimSpace(text), "[[") && len(parts) == 2 {
+				count[parts[1]]++
+			}
+			if table == key.String() && inRequest(key, count, index) {
+				return line
+			}
+			continue
+		}
+
+		m := tomlKeyRe.FindStringSubmatch(text)
+		if m == nil || table != parent || !inRequest(key, count, index) {
+			continue
+		}
+		if m[1]+m[2]+m[3] == name {
+			return line
+		}
+	}
+	return 0
+}
+
+func inRequest(key toml.Key, count map[string]int, index int) bool {
+	return index < 0 || len(key) < 2 || count[key[1]]-1 == index
+}
+
+// UnmarshalYAMLStrict is like UnmarshalYAML, but also rejects keys
+// that don't correspond to a field.
+func UnmarshalYAMLStrict(data []byte, tf *TestFile) error {
+	if err := yaml.UnmarshalStrict(data, tf); err != nil {
+		var msgs []string
+		if te, ok := err.(*yaml.TypeError); ok {
+			msgs = te.Errors
+		} else {
+			msgs = []string{err.Error()}
+		}
+
+		var errs LintErrors
+		for _, msg := range msgs {
+			e := LintError{Path: tf.Path, Msg: msg}
+			if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+				e.Line, _ = strconv.Atoi(m[1])
+				e.Msg = m[2]
+			}
+			errs = append(errs, e)
+		}
+		return errs
+	}
+	return UnmarshalYAML(data, tf)
+}
+
+// methods are the HTTP methods a request may use.
+var methods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "TRACE": true, "CONNECT": true,
+}
+
+// tplRe matches template actions, which are replaced with a
+// placeholder when checking URLs.
+var tplRe = regexp.MustCompile(`{{.*?}}`)
+
+// Validate checks the requests in the file for problems that would
+// otherwise only show up when they're run.
+func (tf *TestFile) Validate() error {
+	var errs LintErrors
+	for _, section := range []string{SectionSetup, SectionTest, SectionTeardown, SectionRequest} {
+		for _, r := range tf.Litmus.Section(section) {
+			for _, err := range r.validate() {
+				errs = append(errs, LintError{Path: tf.Path, Test: r.Name, Msg: err.Error()})
+			}
+		}
+	}
+	return errs.ErrOrNil()
+}
+
+func (r *RequestTest) validate() (errs []error) {
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if r.Name == "" {
+		add(errors.New("missing name"))
+	}
+	if r.Method != "" && !methods[r.Method] {
+		add(errors.Errorf("invalid method %q", r.Method))
+	}
+	if r.URL == "" {
+		add(errors.New("missing url"))
+	} else if _, err := url.Parse(tplRe.ReplaceAllString(r.URL, "x")); err != nil {
+		add(errors.Wrap(err, "invalid url"))
+	}
+	if r.WantsCode != 0 && (r.WantsCode < 100 || r.WantsCode > 599) {
+		add(errors.Errorf("invalid wants_code %d", r.WantsCode))
+	}
+	if r.BodyType != "" {
+		if _, err := NewBodyGetterForType(r.BodyType); err != nil {
+			add(errors.Errorf("invalid body_type %q", r.BodyType))
+		}
+	}
+	add(errors.Wrap(r.Retry.Validate(), "retry"))
+	add(errors.Wrap(r.Until.Validate(), "until"))
+
+	r.walkTemplates(true, true, func(s string) {
+		if _, err := newTemplate(s); err != nil {
+			add(errors.Wrapf(err, "invalid template %q", s))
+		}
+	})
+
+	for _, entries := range []struct {
+		kind string
+		m    map[string]interface{}
+	}{{"head", r.Head}, {"body", r.Body}} {
+		for _, k := range orderedKeys(entries.m, nil) {
+			a, err := extractAssertion(k, entries.m[k])
+			if err == nil {
+				err = a.validate()
+			}
+			add(errors.Wrapf(err, "%s entry %q", entries.kind, k))
+		}
+	}
+
+	for i := range r.Getters {
+		gc := &r.Getters[i]
+		if _, ok := getters[strings.ToLower(gc.Type)]; !ok {
+			add(errors.Errorf("getter %d: unknown type %q", i+1, gc.Type))
+		}
+		if _, err := gc.assertion(); err != nil {
+			add(errors.Wrapf(err, "getter %d", i+1))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestUnmarshalTOMLStrict(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		exp  []string
+	}{
+		{
+			name: "valid",
+			data: `
+[[litmus.test]]
+name="get"
+url="http://x"
+wants_code=200
+[litmus.test.head]
+content_type = {Content-Type = "application/json"}
+[litmus.test.body]
+id = "1"
+count = {op = "gt", value = 1}`,
+		},
+		{
+			name: "unknown field",
+			data: `
+[[litmus.test]]
+name="first"
+url="http://x"
+
+[[litmus.test]]
+name="second"
+url="http://x"
+want_code=200`,
+			exp: []string{`a_test.toml:9: "second": unknown field "want_code"`},
+		},
+		{
+			name: "unknown table",
+			data: `
+[[litmus.test]]
+name="get"
+url="http://x"
+[litmus.test.retries]
+attempts=3`,
+			exp: []string{`a_test.toml:5: "get": unknown field "retries"`},
+		},
+		{
+			name: "unknown nested field",
+			data: `
+[[litmus.test]]
+name="get"
+url="http://x"
+[litmus.test.retry]
+attempt=3`,
+			exp: []string{`a_test.toml:6: "get": unknown field "attempt"`},
+		},
+		{
+			name: "wrong type",
+			data: `
+[[litmus.test]]
+name="post"
+url="http://x"
+body='''{"id": 1}'''`,
+			exp: []string{`a_test.toml:5: "post": body must be a table, got string`},
+		},
+		{
+			name: "syntax error",
+			data: `
+[[litmus.test]]
+name="get`,
+			exp: []string{`a_test.toml:3: `},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tf := TestFile{Path: "a_test.toml"}
+			err := UnmarshalTOMLStrict([]byte(c.data), &tf)
+			if c.exp == nil {
+				test.ErrorNil(t, err)
+				return
+			}
+			errs, ok := err.(LintErrors)
+			test.Assert(t, ok)
+			test.Equals(t, len(c.exp), len(errs))
+			for i, exp := range c.exp {
+				test.Assert(t, strings.HasPrefix(errs[i].Error(), exp))
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAMLStrict(t *testing.T) {
+	tf := TestFile{Path: "a_test.yaml"}
+	err := UnmarshalYAMLStrict([]byte(`
+litmus:
+  test:
+  - name: get
+    url: http://x
+    want_code: 200`), &tf)
+
+	errs, ok := err.(LintErrors)
+	test.Assert(t, ok)
+	test.Equals(t, 1, len(errs))
+	test.Equals(t, 6, errs[0].Line)
+	test.Assert(t, strings.Contains(errs[0].Msg, "want_code"))
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		req  RequestTest
+		exp  string
+	}{
+		{
+			name: "valid",
+			req: RequestTest{
+				Name: "get", Method: "GET", URL: "{{.base}}/orders?id={{.id}}", WantsCode: 200,
+				Body: map[string]interface{}{"id": "1", "count": map[string]interface{}{"op": "gt", "value": 1}},
+			},
+		},
+		{
+			name: "missing name",
+			req:  RequestTest{URL: "http://x"},
+			exp:  `a_test.toml: missing name`,
+		},
+		{
+			name: "invalid method",
+			req:  RequestTest{Name: "get", Method: "get", URL: "http://x"},
+			exp:  `a_test.toml: "get": invalid method "get"`,
+		},
+		{
+			name: "missing url",
+			req:  RequestTest{Name: "get"},
+			exp:  `a_test.toml: "get": missing url`,
+		},
+		{
+			name: "invalid url",
+			req:  RequestTest{Name: "get", URL: "http://x:port"},
+			exp:  `a_test.toml: "get": invalid url`,
+		},
+		{
+			name: "invalid code",
+			req:  RequestTest{Name: "get", URL: "http://x", WantsCode: 2000},
+			exp:  `a_test.toml: "get": invalid wants_code 2000`,
+		},
+		{
+			name: "invalid template",
+			req:  RequestTest{Name: "get", URL: "http://x/{{.id"},
+			exp:  `a_test.toml: "get": invalid template`,
+		},
+		{
+			name: "malformed assertion",
+			req:  RequestTest{Name: "get", URL: "http://x", Body: map[string]interface{}{"id": map[string]interface{}{"op": "bigger"}}},
+			exp:  `a_test.toml: "get": body entry "id"`,
+		},
+		{
+			name: "unknown getter",
+			req:  RequestTest{Name: "get", URL: "http://x", Getters: []GetterConfig{{Type: "xml"}}},
+			exp:  `a_test.toml: "get": getter 1: unknown type "xml"`,
+		},
+		{
+			name: "invalid retry",
+			req:  RequestTest{Name: "get", URL: "http://x", Retry: &RetryPolicy{Attempts: -1}},
+			exp:  `a_test.toml: "get": retry: attempts must not be negative`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tf := TestFile{Path: "a_test.toml", Litmus: Litmus{Test: []RequestTest{c.req}}}
+			err := tf.Validate()
+			if c.exp == "" {
+				test.ErrorNil(t, err)
+				return
+			}
+			test.Assert(t, err != nil)
+			test.Assert(t, strings.HasPrefix(err.Error(), c.exp))
+		})
+	}
+}
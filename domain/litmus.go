@@ -3,7 +3,7 @@ package domain
 
 import (
 	"bytes"
-	"fmt"
+	"regexp"
 	"text/template"
 
 	"github.com/tidwall/sjson"
@@ -11,6 +11,9 @@ import (
 
 // TestFile is the top level container element defining a Litmus test file
 type TestFile struct {
+	// Path is the location the file was loaded from
+	Path string `toml:"-" yaml:"-"`
+
 	// Litmus is the top level table
 	Litmus Litmus
 }
@@ -18,6 +21,53 @@ type TestFile struct {
 type Litmus struct {
 	// test is singular to enable singular dot notation in the file
 	Test []RequestTest
+
+	// Setup requests are made before the tests, and Teardown
+	// requests after them, even if tests fail.
+	Setup    []RequestTest
+	Teardown []RequestTest
+
+	// Request holds named requests that tests can run as
+	// before and after hooks.
+	Request []RequestTest
+
+	// Defaults fills in the values the file's requests don't set.
+	Defaults Defaults
+
+	// Templates are named requests that requests can extend,
+	// inheriting the fields they don't set.
+	Templates map[string]RequestTest
+
+	// Include lists files, relative to this one, whose requests,
+	// templates and defaults are added to this file's.
+	Include []string
+}
+
+// Sections of a test file holding requests.
+const (
+	SectionTest     = "test"
+	SectionSetup    = "setup"
+	SectionTeardown = "teardown"
+	SectionRequest  = "request"
+)
+
+// sectionTemplates is the table of a test file holding templates,
+// keyed by name rather than listed like the sections of requests.
+const sectionTemplates = "templates"
+
+// Section returns the requests in the named section.
+func (l *Litmus) Section(name string) []RequestTest {
+	switch name {
+	case SectionTest:
+		return l.Test
+	case SectionSetup:
+		return l.Setup
+	case SectionTeardown:
+		return l.Teardown
+	case SectionRequest:
+		return l.Request
+	}
+	return nil
 }
 
 // RequestTest defines all the necessary fields to define a Litmus test
@@ -32,6 +82,87 @@ type RequestTest struct {
 	Body          map[string]interface{} `toml:"body" yaml:"body"`
 	Head          map[string]interface{} `toml:"head" yaml:"head"`
 	WantsCode     int                    `toml:"wants_code" yaml:"wants_code"`
+	Getters       GetterConfigs          `toml:"getters" yaml:"getters"`
+	Cookies       map[string]string      `toml:"cookies" yaml:"cookies"`
+	DependsOn     []string               `toml:"depends_on" yaml:"depends_on"`
+	Tags          []string               `toml:"tags" yaml:"tags"`
+
+	// Skip, if set, is the reason the test isn't run.
+	Skip string `toml:"skip" yaml:"skip"`
+
+	// Only restricts the run to the tests that set it.
+	Only bool `toml:"only" yaml:"only"`
+
+	// Before and After name requests, from the file's or the
+	// suite's [[litmus.request]] blocks, to make before and
+	// after the test. After requests are made even if the
+	// test fails.
+	Before []string `toml:"before" yaml:"before"`
+	After  []string `toml:"after" yaml:"after"`
+
+	// Auth sets the Authorization header.
+	Auth *Auth `toml:"auth" yaml:"auth"`
+
+	// UnsetHeaders removes headers, such as those inherited
+	// from defaults, from the request.
+	UnsetHeaders []string `toml:"unset_headers" yaml:"unset_headers"`
+
+	// Vars are template variables for this test only, taking
+	// precedence over the environment.
+	Vars map[string]interface{} `toml:"vars" yaml:"vars"`
+
+	// Extends names the template the request inherits the
+	// fields it doesn't set from.
+	Extends string `toml:"extends" yaml:"extends"`
+
+	// Each and Matrix repeat the test for rows of data, and for
+	// every combination of the listed values, with the values
+	// added to Vars.
+	Each   *Each                    `toml:"each" yaml:"each"`
+	Matrix map[string][]interface{} `toml:"matrix" yaml:"matrix"`
+
+	// ClearCookies empties the cookie jar before the test is run.
+	ClearCookies bool `toml:"clear_cookies" yaml:"clear_cookies"`
+
+	// BodyType forces the getter used for body assertions
+	// (json, xml, html or text) when the server's Content-Type
+	// can't be relied upon.
+	BodyType string `toml:"body_type" yaml:"body_type"`
+
+	// Retry repeats the request when it can't be sent or no
+	// response is received.
+	Retry *RetryPolicy `toml:"retry" yaml:"retry"`
+
+	// Until repeats the request until its assertions pass.
+	Until *RetryPolicy `toml:"until" yaml:"until"`
+
+	// Timeout limits the time the test may take, including any
+	// retries but not its delays.
+	Timeout Duration `toml:"timeout" yaml:"timeout"`
+
+	// DelayBefore and DelayAfter pause the run before and after
+	// the test.
+	DelayBefore Duration `toml:"delay_before" yaml:"delay_before"`
+	DelayAfter  Duration `toml:"delay_after" yaml:"delay_after"`
+
+	// bodyOrder and headOrder hold the keys of Body and Head
+	// in the order they were declared in the test file.
+	bodyOrder []string
+	headOrder []string
+
+	// baseName is the name of the data-driven test this test was
+	// generated from.
+	baseName string
+}
+
+// BaseName returns the name of the data-driven test the test was
+// generated from, such as "create" for "create [1]", or its own
+// name if it wasn't generated.
+func (r *RequestTest) BaseName() string {
+	if r.baseName != "" {
+		return r.baseName
+	}
+	return r.Name
 }
 
 // GetterConfigs is a slice of GetterConfig
@@ -40,12 +171,16 @@ type GetterConfigs []GetterConfig
 // GetterConfig provides the information required
 // to get data from a response.
 type GetterConfig struct {
-	Path     string `toml:"path" yaml:"path"`
-	Set      string `toml:"set" yaml:"set"`
-	Type     string `toml:"type" yaml:"type"`
-	Expected string `toml:"exp" yaml:"exp"`
+	Path     string      `toml:"path" yaml:"path"`
+	Set      string      `toml:"set" yaml:"set"`
+	Type     string      `toml:"type" yaml:"type"`
+	Op       string      `toml:"op" yaml:"op"`
+	Expected interface{} `toml:"exp" yaml:"exp"`
 }
 
+// ApplyEnv applies the environment to the request. Head, body and
+// getter assertions aren't affected; they're templated as they're
+// evaluated, so they can use values captured earlier in the test.
 func (r *RequestTest) ApplyEnv(env map[string]interface{}) (err error) {
 	if r.URL, err = applyTpl(r.URL, env); err != nil {
 		return
@@ -58,89 +193,170 @@ func (r *RequestTest) ApplyEnv(env map[string]interface{}) (err error) {
 			return
 		}
 	}
-	for k, v := range r.Headers {
-		if r.Headers[k], err = applyTpl(v, env); err != nil {
-			return
-		}
+	if r.Headers, err = applyMapEnv(r.Headers, env); err != nil {
+		return
 	}
-	for k, v := range r.Query {
-		if r.Query[k], err = applyTpl(v, env); err != nil {
-			return
-		}
+	if r.Query, err = applyMapEnv(r.Query, env); err != nil {
+		return
 	}
-
-	if err := modifyRequestEnv(r.Body, env); err != nil {
-		return err
+	if r.Cookies, err = applyMapEnv(r.Cookies, env); err != nil {
+		return
 	}
-	if err := modifyRequestEnv(r.Head, env); err != nil {
-		return err
+	if r.Auth != nil {
+		auth := *r.Auth
+		for _, field := range []*string{&auth.Bearer, &auth.Username, &auth.Password} {
+			if *field, err = applyTpl(*field, env); err != nil {
+				return
+			}
+		}
+		r.Auth = &auth
 	}
+
 	return
 }
 
-func modifyRequestEnv(requestEnv map[string]interface{}, globalEnv map[string]interface{}) error {
-	for k, v := range requestEnv {
-		// handle maps
-		if x, ok := v.(map[string]interface{}); ok {
-			if err := handleMap(x, globalEnv); err != nil {
-				return err
-			}
-			continue
-		}
-
-		// otherwise continue as normal
-		key, err := applyTpl(k, globalEnv)
-		if err != nil {
-			return err
-		}
+// applyMapEnv returns a copy of m with the environment applied to
+// its values, leaving m untouched so a request can be reused.
+func applyMapEnv(m map[string]string, env map[string]interface{}) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
 
-		val := fmt.Sprintf("%v", v)
-		value, err := applyTpl(val, globalEnv)
-		if err != nil {
-			return err
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		var err error
+		if out[k], err = applyTpl(v, env); err != nil {
+			return nil, err
 		}
+	}
+	return out, nil
+}
 
-		requestEnv[key] = value
-		if key != k {
-			delete(requestEnv, k)
-		}
+func (gc *GetterConfig) applyEnv(env map[string]interface{}) (err error) {
+	if gc.Path, err = applyTpl(gc.Path, env); err != nil {
+		return
 	}
-	return nil
+	gc.Expected, err = applyTplValue(gc.Expected, env)
+	return
 }
 
-func handleMap(m, globalEnv map[string]interface{}) error {
+// applyEntryEnv applies the environment to a head or body entry.
+// A templated copy of the entry is returned, leaving the test's
+// own entry untouched.
+func applyEntryEnv(key string, value interface{}, env map[string]interface{}) (string, interface{}, error) {
+	key, err := applyTpl(key, env)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// YAML unmarshals maps with interface keys.
+	if x, ok := value.(map[interface{}]interface{}); ok {
+		value = convertInterfaceMap(x)
+	}
+
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		value, err = applyTplValue(value, env)
+		return key, value, err
+	}
+
+	out := make(map[string]interface{}, len(m))
 	for k, v := range m {
-		key, err := applyTpl(k, globalEnv)
-		if err != nil {
-			return err
+		if k, err = applyTpl(k, env); err != nil {
+			return "", nil, err
 		}
-
-		val, ok := v.(string)
-		if !ok {
-			return fmt.Errorf("expected string but got %T", val)
+		if out[k], err = applyTplValue(v, env); err != nil {
+			return "", nil, err
 		}
+	}
+	return key, out, nil
+}
+
+// singleKeyTpl matches a template consisting solely of a
+// reference to a single environment key.
+var singleKeyTpl = regexp.MustCompile(`^{{\s*\.(\w+)\s*}}$`)
 
-		result, err := applyTpl(val, globalEnv)
-		if err != nil {
-			return err
+// applyTplValue applies the environment to strings, including
+// those nested in lists, leaving all other values untouched.
+// A string that only references a single environment key is
+// replaced by that key's value, preserving its type.
+func applyTplValue(v interface{}, env map[string]interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case string:
+		if m := singleKeyTpl.FindStringSubmatch(x); m != nil {
+			if val, ok := env[m[1]]; ok {
+				return val, nil
+			}
 		}
-		m[key] = result
-		if key != k {
-			delete(m, k)
+		return applyTpl(x, env)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			val, err := applyTplValue(item, env)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
 		}
+		return out, nil
+	default:
+		return v, nil
 	}
-	return nil
+}
+
+// strictTemplates makes referencing a key that isn't in the
+// environment an error, rather than rendering "<no value>".
+var strictTemplates = true
+
+// SetStrictTemplates sets whether templates may reference keys that
+// aren't in the environment.
+func SetStrictTemplates(strict bool) {
+	strictTemplates = strict
+}
+
+// newTemplate parses a template used in a test file.
+func newTemplate(input string) (*template.Template, error) {
+	t := template.New("anon").Funcs(templateFuncs)
+	if strictTemplates {
+		t = t.Option("missingkey=error")
+	}
+	return t.Parse(input)
 }
 
 func applyTpl(input string, env map[string]interface{}) (output string, err error) {
 	buf := &bytes.Buffer{}
-	t, err := template.New("anon").Parse(input)
+	t, err := newTemplate(input)
 	if err != nil {
 		return "", err
 	}
-	if err = t.Execute(buf, env); err != nil {
+	if err = t.Execute(buf, withOptionalKeys(input, env)); err != nil {
 		return
 	}
 
 	return buf.String(), nil
 }
+
+// withOptionalKeys returns env with any keys the template only
+// passes to the default or required functions added as nil, so
+// they can be handled by those functions rather than failing.
+func withOptionalKeys(input string, env map[string]interface{}) map[string]interface{} {
+	_, optional := templateRefs(input)
+
+	var out map[string]interface{}
+	for _, k := range optional {
+		if _, ok := env[k]; ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(env)+len(optional))
+			for ek, ev := range env {
+				out[ek] = ev
+			}
+		}
+		out[k] = nil
+	}
+	if out == nil {
+		return env
+	}
+	return out
+}
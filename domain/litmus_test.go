@@ -1,26 +1,99 @@
 package domain
 
 import (
+	"io/ioutil"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/LUSHDigital/litmus/test"
 )
 
-func Test_ExpandEnv(t *testing.T) {
-	globalEnv := map[string]interface{}{
-		"key1": "value1",
-		"key2": "value2",
+func TestApplyEntryEnv(t *testing.T) {
+	env := map[string]interface{}{
+		"key1":   "value1",
+		"key2":   "value2",
+		"id":     int64(42),
+		"active": true,
 	}
-	requestEnv := map[string]interface{}{
-		"key3":      123,
-		"key4":      "{{.key1}}",
-		"{{.key2}}": "whatever",
+
+	tests := []struct {
+		name     string
+		key      string
+		value    interface{}
+		expKey   string
+		expValue interface{}
+	}{
+		{name: "untemplated", key: "key3", value: 123, expKey: "key3", expValue: 123},
+		{name: "templated value", key: "key4", value: "{{.key1}}", expKey: "key4", expValue: "value1"},
+		{name: "templated key", key: "{{.key2}}", value: "whatever", expKey: "value2", expValue: "whatever"},
+		{name: "typed value", key: "id", value: "{{.id}}", expKey: "id", expValue: int64(42)},
+		{name: "typed value with spaces", key: "active", value: "{{ .active }}", expKey: "active", expValue: true},
+		{name: "string value", key: "path", value: "/items/{{.id}}", expKey: "path", expValue: "/items/42"},
+		{
+			name:     "setter",
+			key:      "setter",
+			value:    map[string]interface{}{"{{.key1}}": "{{.id}}"},
+			expKey:   "setter",
+			expValue: map[string]interface{}{"value1": int64(42)},
+		},
+		{
+			name:     "yaml setter",
+			key:      "setter",
+			value:    map[interface{}]interface{}{"id": "{{.id}}"},
+			expKey:   "setter",
+			expValue: map[string]interface{}{"id": int64(42)},
+		},
 	}
-	if err := modifyRequestEnv(requestEnv, globalEnv); err != nil {
-		t.Fatal(err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, value, err := applyEntryEnv(tt.key, tt.value, env)
+			test.ErrorNil(t, err)
+			test.Equals(t, tt.expKey, key)
+			test.Equals(t, tt.expValue, value)
+		})
 	}
+}
+
+func TestOrderedCaptures(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"X-Request-Id": {"req-1"},
+			"X-Echo":       {"req-1"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(`{"id":"abc","alpha":"abc"}`)),
+	}
+
+	// Each capture is declared before the entry using it, but sorts
+	// after it, so the entries must be evaluated in declared order.
+	r := &RequestTest{
+		Head: map[string]interface{}{
+			"request_id": map[string]interface{}{"X-Request-Id": "req-1"},
+			"X-Echo":     "{{.request_id}}",
+		},
+		Body: map[string]interface{}{
+			"zulu":  map[string]interface{}{"id": "abc"},
+			"alpha": "{{.zulu}}",
+		},
+		headOrder: []string{"request_id", "X-Echo"},
+		bodyOrder: []string{"zulu", "alpha"},
+	}
+	test.Equals(t, []string{"request_id", "X-Echo"}, orderedKeys(r.Head, r.headOrder))
+	test.Equals(t, []string{"zulu", "alpha"}, orderedKeys(r.Body, r.bodyOrder))
+
+	env := map[string]interface{}{}
+	test.ErrorNil(t, Header(r, resp, env))
+	test.ErrorNil(t, Body(r, resp, env))
+	test.Equals(t, "req-1", env["request_id"])
+	test.Equals(t, "abc", env["zulu"])
+}
+
+func TestApplyEnvLeavesMapsUntouched(t *testing.T) {
+	headers := map[string]string{"Authorization": "Bearer {{.token}}"}
+	r := RequestTest{Headers: headers}
 
-	test.Equals(t, "whatever", requestEnv["value2"])
-	test.Equals(t, "123", requestEnv["key3"])
-	test.Equals(t, "value1", requestEnv["key4"])
+	test.ErrorNil(t, r.ApplyEnv(map[string]interface{}{"token": "abc"}))
+	test.Equals(t, "Bearer abc", r.Headers["Authorization"])
+	test.Equals(t, "Bearer {{.token}}", headers["Authorization"])
 }
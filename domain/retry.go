@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Backoff strategies for repeated requests.
+const (
+	BackoffFixed       = "fixed"
+	BackoffExponential = "exponential"
+)
+
+// DefaultRetryDelay is the wait before a request is repeated when
+// a policy doesn't set one.
+const DefaultRetryDelay = time.Second
+
+// Duration is a time.Duration written in a test file as a string,
+// such as "500ms" or "2s".
+type Duration time.Duration
+
+// UnmarshalText parses a duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// RetryPolicy describes how often, and for how long, a request is
+// repeated.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times the request is made,
+	// including the first. Zero means no limit if a deadline is set,
+	// otherwise the request is made once.
+	Attempts int `toml:"attempts" yaml:"attempts"`
+
+	// Backoff is fixed (the default) or exponential, which doubles
+	// the delay after each attempt, up to MaxDelay.
+	Backoff  string   `toml:"backoff" yaml:"backoff"`
+	Delay    Duration `toml:"delay" yaml:"delay"`
+	MaxDelay Duration `toml:"max_delay" yaml:"max_delay"`
+
+	// Jitter randomly varies each delay by up to this fraction of it.
+	Jitter float64 `toml:"jitter" yaml:"jitter"`
+
+	// Deadline limits the total time spent on the request,
+	// including waits.
+	Deadline Duration `toml:"deadline" yaml:"deadline"`
+}
+
+// Validate checks the policy's settings.
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch {
+	case p.Attempts < 0:
+		return errors.Errorf("attempts must not be negative, got %d", p.Attempts)
+	case p.Backoff != "" && p.Backoff != BackoffFixed && p.Backoff != BackoffExponential:
+		return errors.Errorf("unknown backoff %q", p.Backoff)
+	case p.Delay < 0 || p.MaxDelay < 0 || p.Deadline < 0:
+		return errors.New("durations must not be negative")
+	case p.Jitter < 0 || p.Jitter > 1:
+		return errors.Errorf("jitter must be between 0 and 1, got %v", p.Jitter)
+	}
+	return nil
+}
+
+// Allows reports whether attempt (counting from 1) may be made.
+// A nil policy allows a single attempt.
+func (p *RetryPolicy) Allows(attempt int) bool {
+	if p == nil {
+		return attempt == 1
+	}
+	if p.Attempts == 0 {
+		return attempt == 1 || p.Deadline > 0
+	}
+	return attempt <= p.Attempts
+}
+
+// Wait returns how long to wait after the given attempt (counting
+// from 1) before making the next one. rnd is a random number in
+// [0, 1) used to apply jitter.
+func (p *RetryPolicy) Wait(attempt int, rnd float64) time.Duration {
+	delay := time.Duration(p.Delay)
+	if delay == 0 {
+		delay = DefaultRetryDelay
+	}
+
+	if p.Backoff == BackoffExponential {
+		delay = time.Duration(float64(delay) * math.Pow(2, float64(attempt-1)))
+	}
+	if p.MaxDelay > 0 && delay > time.Duration(p.MaxDelay) {
+		delay = time.Duration(p.MaxDelay)
+	}
+
+	return time.Duration(float64(delay) * (1 + p.Jitter*(2*rnd-1)))
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		rnd     float64
+		exp     time.Duration
+	}{
+		{name: "default delay", policy: RetryPolicy{}, attempt: 1, rnd: 0.5, exp: DefaultRetryDelay},
+		{name: "fixed", policy: RetryPolicy{Delay: Duration(100 * time.Millisecond)}, attempt: 3, rnd: 0.5, exp: 100 * time.Millisecond},
+		{name: "exponential", policy: RetryPolicy{Backoff: BackoffExponential, Delay: Duration(100 * time.Millisecond)}, attempt: 3, rnd: 0.5, exp: 400 * time.Millisecond},
+		{name: "max delay", policy: RetryPolicy{Backoff: BackoffExponential, Delay: Duration(100 * time.Millisecond), MaxDelay: Duration(250 * time.Millisecond)}, attempt: 3, rnd: 0.5, exp: 250 * time.Millisecond},
+		{name: "jitter low", policy: RetryPolicy{Delay: Duration(100 * time.Millisecond), Jitter: 0.5}, attempt: 1, rnd: 0, exp: 50 * time.Millisecond},
+		{name: "jitter high", policy: RetryPolicy{Delay: Duration(100 * time.Millisecond), Jitter: 0.5}, attempt: 1, rnd: 1, exp: 150 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.Equals(t, tt.exp, tt.policy.Wait(tt.attempt, tt.rnd))
+		})
+	}
+}
+
+func TestRetryPolicyAllows(t *testing.T) {
+	var none *RetryPolicy
+	test.Equals(t, true, none.Allows(1))
+	test.Equals(t, false, none.Allows(2))
+
+	p := &RetryPolicy{Attempts: 3}
+	test.Equals(t, true, p.Allows(3))
+	test.Equals(t, false, p.Allows(4))
+
+	p = &RetryPolicy{}
+	test.Equals(t, false, p.Allows(2))
+
+	p = &RetryPolicy{Deadline: Duration(time.Second)}
+	test.Equals(t, true, p.Allows(100))
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		err    string
+	}{
+		{name: "valid", policy: RetryPolicy{Attempts: 3, Backoff: BackoffExponential, Jitter: 0.1}},
+		{name: "negative attempts", policy: RetryPolicy{Attempts: -1}, err: "attempts must not be negative, got -1"},
+		{name: "unknown backoff", policy: RetryPolicy{Backoff: "linear"}, err: `unknown backoff "linear"`},
+		{name: "jitter", policy: RetryPolicy{Jitter: 2}, err: "jitter must be between 0 and 1, got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.err == "" {
+				test.ErrorNil(t, err)
+				return
+			}
+			test.Assert(t, err != nil)
+			test.Equals(t, tt.err, err.Error())
+		})
+	}
+}
+
+func TestUnmarshalRetryPolicy(t *testing.T) {
+	tomlData := `
+[[litmus.test]]
+name="poll"
+[litmus.test.retry]
+attempts=3
+delay="100ms"
+[litmus.test.until]
+backoff="exponential"
+delay="250ms"
+max_delay="2s"
+jitter=0.1
+deadline="30s"`
+
+	yamlData := `
+litmus:
+  test:
+  - name: poll
+    retry: {attempts: 3, delay: 100ms}
+    until: {backoff: exponential, delay: 250ms, max_delay: 2s, jitter: 0.1, deadline: 30s}`
+
+	var tomlFile, yamlFile TestFile
+	test.ErrorNil(t, UnmarshalTOML([]byte(tomlData), &tomlFile))
+	test.ErrorNil(t, UnmarshalYAML([]byte(yamlData), &yamlFile))
+
+	for name, tf := range map[string]TestFile{"toml": tomlFile, "yaml": yamlFile} {
+		t.Run(name, func(t *testing.T) {
+			r := tf.Litmus.Test[0]
+			test.Equals(t, &RetryPolicy{Attempts: 3, Delay: Duration(100 * time.Millisecond)}, r.Retry)
+			test.Equals(t, &RetryPolicy{
+				Backoff:  BackoffExponential,
+				Delay:    Duration(250 * time.Millisecond),
+				MaxDelay: Duration(2 * time.Second),
+				Jitter:   0.1,
+				Deadline: Duration(30 * time.Second),
+			}, r.Until)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+)
+
+// schemaVersion is the JSON Schema draft the generated schemas use.
+// Draft 7 is the most widely supported by editors.
+const schemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// durationPattern matches the durations accepted by Duration.
+const durationPattern = `^(0|([0-9]*\.?[0-9]+(ns|us|µs|ms|s|m|h))+)$`
+
+// schemaDocs describes each field of the types in a test file, keyed
+// by type and field name.
+var schemaDocs = map[string]string{
+	"TestFile.Litmus": "The top level table.",
+
+	"Litmus.Test":      "The tests in the file, run in the order they're declared.",
+	"Litmus.Setup":     "Requests made before the file's tests. In suite.toml, before every test in the suite.",
+	"Litmus.Teardown":  "Requests made after the file's tests, even if they fail. In suite.toml, after every test in the suite.",
+	"Litmus.Request":   "Named requests that tests can run with before and after.",
+	"Litmus.Defaults":  "Values for the requests in the file that don't set their own. In suite.toml, for every file.",
+	"Litmus.Templates": "Named requests that requests can extend, inheriting the fields they don't set. In suite.toml or the fragments folder, shared by every file.",
+	"Litmus.Include":   "Files, relative to this one, whose requests, templates and defaults are added to this file's.",
+
+	"RequestTest.Name":          "The test's name, unique across the suite.",
+	"RequestTest.Method":        "The HTTP method. Defaults to GET.",
+	"RequestTest.URL":           "The URL requested. URLs without a scheme are joined with the defaults' base_url.",
+	"RequestTest.Headers":       "Headers sent with the request.",
+	"RequestTest.Query":         "Query parameters added to the URL.",
+	"RequestTest.Payload":       "The request body.",
+	"RequestTest.BodyModifiers": "Values set in a JSON payload, keyed by their path.",
+	"RequestTest.Body":          "Assertions on the response body, keyed by path or by the environment key they capture into.",
+	"RequestTest.Head":          "Assertions on the response headers, keyed by header name or by the environment key they capture into.",
+	"RequestTest.WantsCode":     "The expected response status code.",
+	"RequestTest.Getters":       "Values extracted from the response, checked and captured in order.",
+	"RequestTest.Cookies":       "Cookies sent with the request.",
+	"RequestTest.DependsOn":     "Tests that must run before this one. A data-driven test's name covers every test generated from it. A failing dependency doesn't stop this test from running.",
+	"RequestTest.Tags":          "Tags for selecting tests with --tags.",
+	"RequestTest.Skip":          "The reason the test isn't run.",
+	"RequestTest.Only":          "Restricts the run to the tests that set it.",
+	"RequestTest.Before":        "Named requests made before the test.",
+	"RequestTest.After":         "Named requests made after the test, even if it fails.",
+	"RequestTest.Auth":          "Credentials sent in the Authorization header.",
+	"RequestTest.UnsetHeaders":  "Headers, such as those inherited from defaults, removed from the request.",
+	"RequestTest.Extends":       "The template the request inherits the fields it doesn't set from.",
+	"RequestTest.Vars":          "Template variables for this test only, taking precedence over the environment.",
+	"RequestTest.Each":          "Rows of data the test is repeated for.",
+	"RequestTest.Matrix":        "Values the test is repeated for every combination of.",
+	"RequestTest.ClearCookies":  "Empties the cookie jar before the test is run.",
+	"RequestTest.BodyType":      "The getter used for body assertions, regardless of the response's Content-Type.",
+	"RequestTest.Retry":         "Repeats the request when it can't be sent or no response is received.",
+	"RequestTest.Until":         "Repeats the request until its assertions pass.",
+	"RequestTest.Timeout":       "The time the test may take, including retries but not delays.",
+	"RequestTest.DelayBefore":   "A pause before the test is run.",
+	"RequestTest.DelayAfter":    "A pause after the test is run.",
+
+	"GetterConfig.Path":     "The location of the value in the response.",
+	"GetterConfig.Set":      "The environment key the value is captured into.",
+	"GetterConfig.Type":     "Where the value is taken from.",
+	"GetterConfig.Op":       "The operator the value is compared with. Defaults to eq.",
+	"GetterConfig.Expected": "The expected value, or operand.",
+
+	"Defaults.BaseURL":   "Joined with request URLs that don't have a scheme, such as /users.",
+	"Defaults.Method":    "The HTTP method of requests that don't set one.",
+	"Defaults.Headers":   "Headers sent with every request, unless the request sets them.",
+	"Defaults.Query":     "Query parameters added to every request, unless the request sets them.",
+	"Defaults.WantsCode": "The expected response status code of requests that don't set one.",
+	"Defaults.Timeout":   "The time limit of requests that don't set one.",
+	"Defaults.Auth":      "Credentials for requests that don't set any.",
+
+	"Auth.Bearer":   "A bearer token.",
+	"Auth.Username": "The username for basic auth.",
+	"Auth.Password": "The password for basic auth.",
+
+	"Each.Rows": "Rows given inline.",
+	"Each.File": "A CSV, JSON or YAML file of rows, relative to the test file.",
+	"Each.ID":   "The field identifying each row in the names of the generated tests. Rows are numbered if it isn't set.",
+
+	"RetryPolicy.Attempts": "The maximum number of times the request is made, including the first.",
+	"RetryPolicy.Backoff":  "How the delay changes between attempts. Defaults to fixed.",
+	"RetryPolicy.Delay":    "The wait between attempts. Defaults to 1s.",
+	"RetryPolicy.MaxDelay": "The longest wait between attempts with exponential backoff.",
+	"RetryPolicy.Jitter":   "The fraction of each delay it's randomly varied by.",
+	"RetryPolicy.Deadline": "The total time spent on the request, including waits.",
+}
+
+// schemaKeywords adds to the schema generated for a field, keyed
+// like schemaDocs.
+var schemaKeywords = map[string]map[string]interface{}{
+	"RequestTest.Method":        {"enum": sortedKeys(methods)},
+	"RequestTest.Body":          {"additionalProperties": schemaRef("Assertion")},
+	"RequestTest.Head":          {"additionalProperties": schemaRef("Assertion")},
+	"RequestTest.WantsCode":     {"minimum": 100, "maximum": 599},
+	"RequestTest.BodyType":      {"examples": []string{BodyTypeJSON, BodyTypeXML, BodyTypeHTML, BodyTypeText}},
+	"RequestTest.BodyModifiers": {"additionalProperties": map[string]interface{}{}},
+	"GetterConfig.Type":         {"examples": getterTypes()},
+	"GetterConfig.Op":           {"examples": sortedKeys(operators)},
+	"Defaults.Method":           {"enum": sortedKeys(methods)},
+	"Defaults.WantsCode":        {"minimum": 100, "maximum": 599},
+	"RetryPolicy.Backoff":       {"enum": []string{BackoffFixed, BackoffExponential}},
+	"RetryPolicy.Attempts":      {"minimum": 0},
+	"RetryPolicy.Jitter":        {"minimum": 0, "maximum": 1},
+}
+
+// assertionSchema describes a head or body entry: an expected value,
+// a capture, or an operator table.
+var assertionSchema = map[string]interface{}{
+	"description": "An expected value; {path = expected} to capture the value at path; or a table with op, value, path and set.",
+	"anyOf": []interface{}{
+		map[string]interface{}{"type": []string{"string", "number", "boolean", "array", "null"}},
+		map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path":  map[string]interface{}{"type": "string", "description": "The location of the value. Defaults to the entry's key."},
+				"op":    map[string]interface{}{"type": "string", "examples": sortedKeys(operators), "description": "The operator the value is compared with. Defaults to eq."},
+				"value": map[string]interface{}{"description": "The operand, where the operator requires one."},
+				"set":   map[string]interface{}{"type": "string", "description": "The environment key the value is captured into."},
+			},
+		},
+	},
+}
+
+// TestFileSchema returns a JSON Schema describing test files,
+// including suite files, generated from the types they decode into.
+func TestFileSchema() map[string]interface{} {
+	b := schemaBuilder{defs: map[string]interface{}{"Assertion": assertionSchema}}
+	b.typeSchema(reflect.TypeOf(TestFile{}))
+
+	schema := map[string]interface{}{
+		"$schema":     schemaVersion,
+		"title":       "litmus test file",
+		"definitions": b.defs,
+	}
+	for k, v := range b.defs["TestFile"].(map[string]interface{}) {
+		schema[k] = v
+	}
+	delete(b.defs, "TestFile")
+	return schema
+}
+
+// EnvSchema returns a JSON Schema describing env files.
+func EnvSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":              schemaVersion,
+		"title":                "litmus env file",
+		"description":          "Values available to the templates in test files, keyed by name.",
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{},
+	}
+}
+
+// schemaBuilder generates schemas for Go types, collecting the
+// schemas of structs as definitions.
+type schemaBuilder struct {
+	defs map[string]interface{}
+}
+
+var durationType = reflect.TypeOf(Duration(0))
+
+func (b *schemaBuilder) typeSchema(t reflect.Type) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{"type": "string", "pattern": durationPattern, "examples": []string{"500ms", "2s", "1m30s"}}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := b.defs[t.Name()]; !ok {
+			b.defs[t.Name()] = nil
+			b.defs[t.Name()] = b.structSchema(t)
+		}
+		return schemaRef(t.Name())
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, f := range schemaFields(t) {
+		key := t.Name() + "." + f.Name
+		s := b.typeSchema(f.Type)
+		if doc, ok := schemaDocs[key]; ok {
+			s["description"] = doc
+		}
+		for k, v := range schemaKeywords[key] {
+			s[k] = v
+		}
+		properties[fieldKey(f)] = s
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// schemaFields returns the fields of t that are decoded from a
+// test file.
+func schemaFields(t reflect.Type) (fields []reflect.StructField) {
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" && fieldKey(f) != "-" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// fieldKey returns the key a field is decoded from.
+func fieldKey(f reflect.StructField) string {
+	if key := strings.Split(f.Tag.Get("toml"), ",")[0]; key != "" {
+		return key
+	}
+	return strings.ToLower(f.Name)
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/definitions/" + name}
+}
+
+// getterTypes returns the types of getter in order.
+func getterTypes() []string {
+	types := make(map[string]bool, len(getters))
+	for g := range getters {
+		types[g] = true
+	}
+	return sortedKeys(types)
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+// TestSchemaDocs checks every field decoded from a test file is
+// described, and that nothing is described that isn't a field.
+func TestSchemaDocs(t *testing.T) {
+	fields := make(map[string]bool)
+	var walk func(reflect.Type)
+	walk = func(typ reflect.Type) {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			walk(typ.Elem())
+		case reflect.Struct:
+			for _, f := range schemaFields(typ) {
+				key := typ.Name() + "." + f.Name
+				if !fields[key] {
+					fields[key] = true
+					walk(f.Type)
+				}
+			}
+		}
+	}
+	walk(reflect.TypeOf(TestFile{}))
+
+	for key := range fields {
+		if _, ok := schemaDocs[key]; !ok {
+			t.Errorf("%s isn't described in schemaDocs", key)
+		}
+	}
+	for key := range schemaDocs {
+		if !fields[key] {
+			t.Errorf("schemaDocs describes unknown field %s", key)
+		}
+	}
+	for key := range schemaKeywords {
+		if !fields[key] {
+			t.Errorf("schemaKeywords refers to unknown field %s", key)
+		}
+	}
+}
+
+func TestTestFileSchema(t *testing.T) {
+	schema := TestFileSchema()
+	test.Equals(t, schemaVersion, schema["$schema"])
+	test.Equals(t, map[string]interface{}{"$ref": "#/definitions/Litmus", "description": "The top level table."}, schema["properties"].(map[string]interface{})["litmus"])
+
+	defs := schema["definitions"].(map[string]interface{})
+	for _, name := range []string{"Litmus", "RequestTest", "GetterConfig", "Defaults", "Auth", "Each", "RetryPolicy", "Assertion"} {
+		_, ok := defs[name]
+		test.Assert(t, ok)
+	}
+	_, ok := defs["TestFile"]
+	test.Assert(t, !ok)
+
+	props := defs["RequestTest"].(map[string]interface{})["properties"].(map[string]interface{})
+	test.Equals(t, len(schemaFields(reflect.TypeOf(RequestTest{}))), len(props))
+	test.Equals(t, "integer", props["wants_code"].(map[string]interface{})["type"])
+	test.Equals(t, durationPattern, props["timeout"].(map[string]interface{})["pattern"])
+	test.Equals(t, schemaRef("Assertion"), props["body"].(map[string]interface{})["additionalProperties"])
+	_, ok = props["bodyOrder"]
+	test.Assert(t, !ok)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedHeaders lists the headers whose values carry credentials
+// and are therefore never printed.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+}
+
+// exchange captures a request and its response so they can be
+// displayed when a test fails or when running in verbose mode.
+type exchange struct {
+	request  *http.Request
+	payload  string
+	response *http.Response
+	body     []byte
+}
+
+// write prints the exchange to w, truncating bodies longer than
+// limit bytes. A limit of zero disables truncation.
+func (e *exchange) write(w io.Writer, limit int) {
+	if e.request != nil {
+		fmt.Fprintf(w, "\t%s\n", blue("REQUEST"))
+		fmt.Fprintf(w, "\t\t%s %s\n", e.request.Method, e.request.URL)
+		writeHeaders(w, e.request.Header)
+		writeBody(w, e.request.Header.Get("Content-Type"), []byte(e.payload), limit)
+	}
+
+	if e.response != nil {
+		fmt.Fprintf(w, "\t%s\n", blue("RESPONSE"))
+		fmt.Fprintf(w, "\t\t%s\n", e.response.Status)
+		writeHeaders(w, e.response.Header)
+		writeBody(w, e.response.Header.Get("Content-Type"), e.body, limit)
+	}
+}
+
+func writeHeaders(w io.Writer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range header[k] {
+			if redactedHeaders[http.CanonicalHeaderKey(k)] {
+				v = "[redacted]"
+			}
+			fmt.Fprintf(w, "\t\t%s: %s\n", k, v)
+		}
+	}
+}
+
+func writeBody(w io.Writer, contentType string, body []byte, limit int) {
+	if len(body) == 0 {
+		return
+	}
+
+	body = prettyBody(contentType, body)
+
+	var truncated int
+	if limit > 0 && len(body) > limit {
+		// Back up to the start of a rune so a multi-byte character
+		// isn't split.
+		for limit > 0 && !utf8.RuneStart(body[limit]) {
+			limit--
+		}
+		truncated = len(body) - limit
+		body = body[:limit]
+	}
+
+	fmt.Fprintln(w)
+	for _, line := range strings.Split(string(body), "\n") {
+		fmt.Fprintf(w, "\t\t%s\n", line)
+	}
+	if truncated > 0 {
+		fmt.Fprintf(w, "\t\t... (%d bytes truncated)\n", truncated)
+	}
+}
+
+// prettyBody indents JSON bodies, returning all other bodies
+// unaltered.
+func prettyBody(contentType string, body []byte) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") && !json.Valid(body) {
+		return body
+	}
+
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, body, "", "  "); err != nil {
+		return body
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestPrettyBody(t *testing.T) {
+	act := prettyBody("application/json; charset=utf-8", []byte(`{"a":1}`))
+	test.Equals(t, "{\n  \"a\": 1\n}", string(act))
+
+	act = prettyBody("text/plain", []byte(`not json`))
+	test.Equals(t, "not json", string(act))
+}
+
+func TestExchangeWriteTruncates(t *testing.T) {
+	u, _ := url.Parse("http://example.com/get")
+	ex := &exchange{
+		request: &http.Request{Method: "GET", URL: u, Header: http.Header{}},
+		response: &http.Response{
+			Status: "200 OK",
+			Header: http.Header{"Content-Type": {"text/plain"}},
+		},
+		body: []byte("0123456789"),
+	}
+
+	buf := &bytes.Buffer{}
+	ex.write(buf, 4)
+
+	out := buf.String()
+	test.Assert(t, strings.Contains(out, "GET http://example.com/get"))
+	test.Assert(t, strings.Contains(out, "Content-Type: text/plain"))
+	test.Assert(t, strings.Contains(out, "\t\t0123\n"))
+	test.Assert(t, strings.Contains(out, "(6 bytes truncated)"))
+}
+
+func TestWriteBodyTruncatesAtRune(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeBody(buf, "text/plain", []byte("aé€b"), 4)
+
+	out := buf.String()
+	test.Assert(t, strings.Contains(out, "\t\taé\n"))
+	test.Assert(t, strings.Contains(out, "(4 bytes truncated)"))
+}
+
+func TestWriteHeadersRedacts(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writeHeaders(buf, http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"session=secret"},
+		"Set-Cookie":    {"session=secret; Path=/"},
+		"Accept":        {"application/json"},
+	})
+
+	out := buf.String()
+	test.Assert(t, !strings.Contains(out, "secret"))
+	test.Assert(t, strings.Contains(out, "Authorization: [redacted]"))
+	test.Assert(t, strings.Contains(out, "Cookie: [redacted]"))
+	test.Assert(t, strings.Contains(out, "Accept: application/json"))
+}
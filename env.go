@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// envFiles are the names of the env file looked for in the config
+// folder when none are given with -u.
+var envFiles = []string{"env.toml", "env.yaml", "env.yml", "env.json"}
+
+// dotenvFile is the name of the file, in the config folder, holding
+// variables added to the OS environment.
+const dotenvFile = ".env"
+
+// envPrefix marks the variables of the OS environment that are
+// added to the test environment.
+const envPrefix = "LITMUS_"
+
+// Layers of the environment that aren't files.
+const (
+	sourceOS   = "os"
+	sourceFlag = "flag"
+)
+
+// environment holds the values templates can use, and the layer
+// each came from.
+type environment struct {
+	values  map[string]interface{}
+	sources map[string]string
+}
+
+func (e *environment) set(key string, value interface{}, source string) {
+	e.values[key] = value
+	e.sources[key] = source
+}
+
+// lookup returns the key of the value named key, ignoring case, or
+// key itself if there isn't one. If several keys differ only in
+// case, key itself is preferred, then the first in sorted order.
+func (e *environment) lookup(key string) string {
+	if _, ok := e.values[key]; ok {
+		return key
+	}
+	for _, k := range e.keys() {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// keys returns the keys of the environment's values in order.
+func (e *environment) keys() []string {
+	keys := make([]string, 0, len(e.values))
+	for k := range e.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// loadEnv builds the environment from its layers, each taking
+// precedence over the last:
+//
+//   - the env files, in order, or the config folder's env file
+//     if none are given; only files given explicitly must exist
+//   - the OS environment's LITMUS_ variables, without the prefix,
+//     including those set by the config folder's .env file
+//   - the variables given with -e
+//
+// The names of OS variables are matched with the keys of the env
+// files ignoring case, and are otherwise lowercased, so that
+// LITMUS_BASE_URL sets base_url. Where they replace a number or
+// boolean from an env file, their values are converted to its type.
+func loadEnv(config string, files []string, vars domain.KeyValuePairs) (*environment, error) {
+	env := &environment{
+		values:  make(map[string]interface{}),
+		sources: make(map[string]string),
+	}
+
+	if len(files) == 0 {
+		for _, name := range envFiles {
+			path := filepath.Join(config, name)
+			if _, err := os.Stat(path); err == nil {
+				files = []string{path}
+				break
+			}
+		}
+	}
+	for _, file := range files {
+		var values map[string]interface{}
+		if err := unmarhsal(file, &values); err != nil {
+			return nil, errors.Wrapf(err, "loading env file %s", file)
+		}
+		for k, v := range values {
+			env.set(k, v, file)
+		}
+	}
+
+	dotenv, err := loadDotenv(filepath.Join(config, dotenvFile))
+	if err != nil {
+		return nil, err
+	}
+	environ := os.Environ()
+	sort.Strings(environ)
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if !strings.HasPrefix(name, envPrefix) || name == envPrefix {
+			continue
+		}
+		source := sourceOS
+		if dotenv[name] {
+			source = filepath.Join(config, dotenvFile)
+		}
+		key := env.lookup(strings.ToLower(strings.TrimPrefix(name, envPrefix)))
+		value, err := convertLike(os.Getenv(name), env.values[key])
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s replaces %s from %s", name, key, env.sources[key])
+		}
+		env.set(key, value, source)
+	}
+
+	for _, kvp := range vars {
+		env.set(kvp.Key, kvp.Value, sourceFlag)
+	}
+	return env, nil
+}
+
+// convertLike converts s to the type of the value it replaces, if
+// that's a number or boolean, so typed assertions still hold.
+func convertLike(s string, old interface{}) (interface{}, error) {
+	switch old.(type) {
+	case int, int64:
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("expected an integer, got %q", s)
+		}
+		if _, ok := old.(int); ok {
+			return int(v), nil
+		}
+		return v, nil
+	case float64:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Errorf("expected a number, got %q", s)
+		}
+		return v, nil
+	case bool:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Errorf("expected a boolean, got %q", s)
+		}
+		return v, nil
+	}
+	return s, nil
+}
+
+// loadDotenv sets the variables in a dotenv file that aren't
+// already set in the OS environment, returning their names. It's
+// not an error for the file not to exist.
+func loadDotenv(path string) (set map[string]bool, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading dotenv file")
+	}
+	defer f.Close()
+
+	vars, err := parseDotenv(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing %s", path)
+	}
+
+	set = make(map[string]bool)
+	for _, kv := range vars {
+		if _, ok := os.LookupEnv(kv.Key); ok {
+			continue
+		}
+		if err = os.Setenv(kv.Key, kv.Value); err != nil {
+			return nil, errors.Wrapf(err, "setting %s", kv.Key)
+		}
+		set[kv.Key] = true
+	}
+	return set, nil
+}
+
+// parseDotenv reads lines of NAME=value, ignoring blank lines and
+// those starting with #. Names may be preceded by export. Values
+// may be quoted: double quoted values are unescaped, and single
+// quoted values taken as they are. Unquoted values end at a #
+// preceded by a space.
+func parseDotenv(r io.Reader) (vars domain.KeyValuePairs, err error) {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		parts := strings.SplitN(text, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, errors.Errorf("line %d: expected NAME=value", line)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, errors.Errorf("line %d: unterminated quoted value", line)
+			}
+			if value, err = strconv.Unquote(value[:end+1]); err != nil {
+				return nil, errors.Errorf("line %d: invalid quoted value", line)
+			}
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, errors.Errorf("line %d: unterminated quoted value", line)
+			}
+			value = value[1 : end+1]
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		vars = append(vars, domain.KeyValuePair{Key: name, Value: value})
+	}
+	return vars, scanner.Err()
+}
+
+// closingQuote returns the index of the double quote closing the
+// one s starts with, or -1 if there isn't one.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// print writes each value of the environment, in order of its key,
+// and with explain, the layer it came from.
+func (e *environment) print(w io.Writer, explain bool) error {
+	keys := e.keys()
+	if !explain {
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s=%v\n", k, e.values[k])
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", k, e.values[k], e.sources[k])
+	}
+	return tw.Flush()
+}
+
+// envCommand returns the command that prints the environment.
+func envCommand() *cobra.Command {
+	var configPath string
+	var targetEnvs []string
+	var eVariables domain.KeyValuePairs
+	var explain bool
+
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: envShort,
+		Long:  envLong,
+		Run: func(cmd *cobra.Command, args []string) {
+			env, err := loadEnv(configPath, targetEnvs, eVariables)
+			if err == nil {
+				err = env.print(os.Stdout, explain)
+			}
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", cFlagUsage)
+	cmd.Flags().StringArrayVarP(&targetEnvs, "using", "u", nil, uFlagUsage)
+	cmd.Flags().VarP(&eVariables, "env", "e", eFlagUsage)
+	cmd.Flags().BoolVar(&explain, "explain", false, explainFlagUsage)
+	cmd.MarkFlagRequired("config")
+
+	return cmd
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := parseDotenv(strings.NewReader(`
+# credentials
+TOKEN=abc
+export USER = admin
+EMPTY=
+URL=http://x/#anchor # the API
+DOUBLE="a \"quoted\"\nvalue" # comment
+SINGLE='a \n value'
+`))
+	test.ErrorNil(t, err)
+	test.Equals(t, domain.KeyValuePairs{
+		{Key: "TOKEN", Value: "abc"},
+		{Key: "USER", Value: "admin"},
+		{Key: "EMPTY", Value: ""},
+		{Key: "URL", Value: "http://x/#anchor"},
+		{Key: "DOUBLE", Value: "a \"quoted\"\nvalue"},
+		{Key: "SINGLE", Value: `a \n value`},
+	}, vars)
+
+	for _, bad := range []string{"TOKEN", "A B=c", `QUOTED="abc`, "QUOTED='abc"} {
+		_, err = parseDotenv(strings.NewReader(bad))
+		test.Assert(t, err != nil)
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"env.toml": `
+base_url="http://default"
+timeout=5`,
+		"staging.yaml": `
+base_url: http://staging
+user: staging`,
+		"local.json": `{"user": "local", "Token": "file"}`,
+		".env": `
+LITMUS_TOKEN=dotenv
+LITMUS_SECRET=dotenv
+LITMUS_DEBUG=dotenv
+SIGNING_KEY=dotenv`,
+	})
+	defer os.RemoveAll(dir)
+
+	for k, v := range map[string]string{"LITMUS_DEBUG": "os", "LITMUS_BASE_URL": "http://os"} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	for _, k := range []string{"LITMUS_TOKEN", "LITMUS_SECRET", "SIGNING_KEY"} {
+		defer os.Unsetenv(k)
+	}
+
+	t.Run("default file", func(t *testing.T) {
+		env, err := loadEnv(dir, nil, domain.KeyValuePairs{{Key: "secret", Value: "flag"}})
+		test.ErrorNil(t, err)
+		test.Equals(t, map[string]interface{}{
+			"base_url": "http://os",
+			"timeout":  int64(5),
+			"token":    "dotenv",
+			"secret":   "flag",
+			"debug":    "os",
+		}, env.values)
+		test.Equals(t, map[string]string{
+			"base_url": sourceOS,
+			"timeout":  filepath.Join(dir, "env.toml"),
+			"token":    filepath.Join(dir, ".env"),
+			"secret":   sourceFlag,
+			"debug":    sourceOS,
+		}, env.sources)
+		test.Equals(t, "dotenv", os.Getenv("SIGNING_KEY"))
+	})
+
+	t.Run("env files", func(t *testing.T) {
+		files := []string{filepath.Join(dir, "staging.yaml"), filepath.Join(dir, "local.json")}
+		env, err := loadEnv(dir, files, nil)
+		test.ErrorNil(t, err)
+		test.Equals(t, "local", env.values["user"])
+		test.Equals(t, files[1], env.sources["user"])
+		test.Equals(t, "dotenv", env.values["Token"])
+		_, ok := env.values["timeout"]
+		test.Assert(t, !ok)
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		empty := writeFiles(t, nil)
+		defer os.RemoveAll(empty)
+
+		env, err := loadEnv(empty, nil, nil)
+		test.ErrorNil(t, err)
+		test.Equals(t, "http://os", env.values["base_url"])
+
+		_, err = loadEnv(empty, []string{filepath.Join(empty, "missing.toml")}, nil)
+		test.Assert(t, err != nil)
+	})
+
+	t.Run("unsupported files", func(t *testing.T) {
+		other := writeFiles(t, map[string]string{"staging.env": "TOKEN=abc"})
+		defer os.RemoveAll(other)
+
+		_, err := loadEnv(other, []string{filepath.Join(other, "staging.env")}, nil)
+		test.Assert(t, err != nil)
+		test.Assert(t, strings.Contains(err.Error(), "unsupported file type"))
+	})
+}
+
+func TestLoadEnvTypedOverrides(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"env.toml": `
+timeout=5
+ratio=0.5
+debug=false
+name="default"`,
+		"env.yaml": "retries: 2",
+	})
+	defer os.RemoveAll(dir)
+
+	vars := map[string]string{
+		"LITMUS_TIMEOUT": "10",
+		"LITMUS_RATIO":   "0.75",
+		"LITMUS_DEBUG":   "true",
+		"LITMUS_NAME":    "42",
+		"LITMUS_RETRIES": "3",
+	}
+	for k, v := range vars {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	env, err := loadEnv(dir, nil, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, int64(10), env.values["timeout"])
+	test.Equals(t, 0.75, env.values["ratio"])
+	test.Equals(t, true, env.values["debug"])
+	test.Equals(t, "42", env.values["name"])
+	test.Equals(t, "3", env.values["retries"])
+
+	env, err = loadEnv(dir, []string{filepath.Join(dir, "env.yaml")}, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, 3, env.values["retries"])
+
+	os.Setenv("LITMUS_TIMEOUT", "soon")
+	_, err = loadEnv(dir, nil, nil)
+	test.Assert(t, err != nil)
+	test.Assert(t, strings.Contains(err.Error(), `expected an integer, got "soon"`))
+}
+
+func TestEnvironmentLookup(t *testing.T) {
+	env := &environment{values: map[string]interface{}{"Token": "a", "TOKEN": "b", "User": "c"}}
+	test.Equals(t, "TOKEN", env.lookup("token"))
+	test.Equals(t, "User", env.lookup("user"))
+	test.Equals(t, "debug", env.lookup("debug"))
+
+	env.values["token"] = "d"
+	test.Equals(t, "token", env.lookup("token"))
+}
+
+func TestEnvironmentPrint(t *testing.T) {
+	env := &environment{
+		values:  map[string]interface{}{"base_url": "http://x", "id": int64(1)},
+		sources: map[string]string{"base_url": "tests/env.toml", "id": sourceFlag},
+	}
+
+	buf := &bytes.Buffer{}
+	test.ErrorNil(t, env.print(buf, false))
+	test.Equals(t, "base_url=http://x\nid=1\n", buf.String())
+
+	buf.Reset()
+	test.ErrorNil(t, env.print(buf, true))
+	test.Equals(t, ""+
+		"KEY       VALUE     SOURCE\n"+
+		"base_url  http://x  tests/env.toml\n"+
+		"id        1         flag\n", buf.String())
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// Phases of the fixture requests made around tests.
+const (
+	phaseSetup    = "setup"
+	phaseTeardown = "teardown"
+)
+
+// reasonSetupFailed is given for tests skipped because a setup
+// request they rely on didn't pass.
+const reasonSetupFailed = "setup failed"
+
+// suiteFiles are the names of the file, in the configuration folder,
+// holding the suite's setup, teardown and shared requests.
+var suiteFiles = []string{"suite.toml", "suite.yaml", "suite.yml", "suite.json"}
+
+// loadSuite loads the suite file from the configuration folder. It
+// returns nil if there isn't one.
+func loadSuite(config string, strict bool) (*domain.TestFile, error) {
+	for _, name := range suiteFiles {
+		path := filepath.Join(config, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+
+		suite := &domain.TestFile{Path: path}
+		err := unmarshalTestFile(path, suite, strict)
+		if err == nil {
+			err = includeFiles(suite, strict)
+		}
+		if err != nil {
+			if _, ok := err.(domain.LintErrors); ok {
+				return nil, err
+			}
+			return nil, errors.Wrapf(err, "loading %s", path)
+		}
+		return suite, nil
+	}
+	return nil, nil
+}
+
+// applyDefaults fills in the values the requests in each file don't
+// set from the file's defaults, then the suite's.
+func applyDefaults(suite *domain.TestFile, files []domain.TestFile) {
+	var parent *domain.Defaults
+	if suite != nil {
+		suite.ApplyDefaults()
+		parent = &suite.Litmus.Defaults
+	}
+	for i := range files {
+		files[i].ApplyDefaults(parent)
+	}
+}
+
+// addFixtures surrounds the tests from each file with the file's
+// setup and teardown requests, and the whole run with the suite's.
+// Fixtures are only added for files with tests to run.
+func addFixtures(suite *domain.TestFile, files []domain.TestFile, tests []*plannedTest) []*plannedTest {
+	if len(tests) == 0 {
+		return tests
+	}
+
+	fixtures := func(tf *domain.TestFile, phase string, requests []domain.RequestTest, suite bool) (planned []*plannedTest) {
+		for _, req := range requests {
+			planned = append(planned, &plannedTest{file: tf.Path, test: req, phase: phase, suite: suite})
+		}
+		return
+	}
+
+	var planned []*plannedTest
+	if suite != nil {
+		planned = append(planned, fixtures(suite, phaseSetup, suite.Litmus.Setup, true)...)
+	}
+	for i := range files {
+		file := &files[i]
+
+		var fileTests []*plannedTest
+		for _, t := range tests {
+			if t.file == file.Path {
+				fileTests = append(fileTests, t)
+			}
+		}
+		if len(fileTests) == 0 {
+			continue
+		}
+
+		planned = append(planned, fixtures(file, phaseSetup, file.Litmus.Setup, false)...)
+		planned = append(planned, fileTests...)
+		planned = append(planned, fixtures(file, phaseTeardown, file.Litmus.Teardown, false)...)
+	}
+	if suite != nil {
+		planned = append(planned, fixtures(suite, phaseTeardown, suite.Litmus.Teardown, true)...)
+	}
+	return planned
+}
+
+// resolveHooks finds the requests named by each test's before and
+// after hooks, looking first in the test's own file and then in the
+// suite file.
+func resolveHooks(suite *domain.TestFile, files []domain.TestFile, tests []*plannedTest) error {
+	named := make(map[string]map[string]domain.RequestTest)
+	add := func(tf *domain.TestFile) {
+		named[tf.Path] = make(map[string]domain.RequestTest)
+		for _, req := range tf.Litmus.Request {
+			named[tf.Path][req.Name] = req
+		}
+	}
+	for i := range files {
+		add(&files[i])
+	}
+	if suite != nil {
+		add(suite)
+	}
+
+	lookup := func(file, name string) (domain.RequestTest, bool) {
+		if req, ok := named[file][name]; ok {
+			return req, true
+		}
+		if suite != nil {
+			req, ok := named[suite.Path][name]
+			return req, ok
+		}
+		return domain.RequestTest{}, false
+	}
+
+	for _, t := range tests {
+		for _, hooks := range []struct {
+			names []string
+			dst   *[]domain.RequestTest
+		}{
+			{t.test.Before, &t.before},
+			{t.test.After, &t.after},
+		} {
+			for _, name := range hooks.names {
+				req, ok := lookup(t.file, name)
+				if !ok {
+					return errors.Errorf("test %q uses unknown request %q", t.test.Name, name)
+				}
+				*hooks.dst = append(*hooks.dst, req)
+			}
+		}
+	}
+	return nil
+}
+
+// linkFixtures makes tests wait for the setup requests they rely
+// on, and teardown requests wait for the tests they clean up after.
+// Suite setup comes before everything and suite teardown after
+// everything; file setup and teardown surround the file's tests.
+func linkFixtures(tests []*plannedTest) {
+	var suiteSetup []int
+	fileSetup := make(map[string][]int)
+	fileMembers := make(map[string][]int)
+
+	for i, t := range tests {
+		deps := []int{}
+		switch {
+		case t.suite && t.phase == phaseSetup:
+			deps = append(deps, suiteSetup...)
+			suiteSetup = append(suiteSetup, i)
+		case t.suite && t.phase == phaseTeardown:
+			for j := 0; j < i; j++ {
+				deps = append(deps, j)
+			}
+		case t.phase == phaseSetup:
+			deps = append(deps, suiteSetup...)
+			deps = append(deps, fileSetup[t.file]...)
+			fileSetup[t.file] = append(fileSetup[t.file], i)
+		case t.phase == phaseTeardown:
+			deps = append(deps, fileMembers[t.file]...)
+		default:
+			deps = append(deps, suiteSetup...)
+			deps = append(deps, fileSetup[t.file]...)
+		}
+
+		if !t.suite {
+			fileMembers[t.file] = append(fileMembers[t.file], i)
+		}
+		t.deps = deps
+	}
+}
+
+// blocked reports whether a test must be skipped because a setup
+// request it relies on didn't pass.
+func blocked(tests []*plannedTest, results []testResult, t *plannedTest) bool {
+	for _, d := range t.deps {
+		if tests[d].phase == phaseSetup && results[d].status != statusPass {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+// recordingServer records the path of every request it receives,
+// failing those under /fail.
+func recordingServer() (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if strings.HasPrefix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "litmus")
+	test.ErrorNil(t, err)
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		test.ErrorNil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		test.ErrorNil(t, ioutil.WriteFile(path, []byte(data), 0644))
+	}
+	return dir
+}
+
+func TestRunRequestsFixtures(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"suite.toml": `
+[[litmus.setup]]
+name="create fixtures"
+method="POST"
+url="{{.base}}/suite/setup"
+wants_code=200
+[[litmus.teardown]]
+name="delete fixtures"
+method="DELETE"
+url="{{.base}}/suite/teardown"
+[[litmus.request]]
+name="log in"
+method="POST"
+url="{{.base}}/login"
+wants_code=200`,
+		"a_test.toml": `
+[[litmus.setup]]
+name="create order"
+method="POST"
+url="{{.base}}/a/setup"
+wants_code=200
+[[litmus.test]]
+name="get order"
+method="GET"
+url="{{.base}}/a/test"
+before=["log in"]
+after=["log out"]
+wants_code=200
+[[litmus.teardown]]
+name="delete order"
+method="DELETE"
+url="{{.base}}/a/teardown"
+[[litmus.request]]
+name="log out"
+method="POST"
+url="{{.base}}/logout"`,
+		"b_test.toml": `
+[[litmus.setup]]
+name="broken setup"
+method="POST"
+url="{{.base}}/fail/setup"
+wants_code=200
+[[litmus.test]]
+name="never run"
+method="GET"
+url="{{.base}}/b/test"
+[[litmus.teardown]]
+name="still cleaned up"
+method="DELETE"
+url="{{.base}}/b/teardown"`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeSuite,
+		parallel:      1,
+		parallelScope: parallelScopeFile,
+	}
+	sum, err := r.runRequests(context.Background(), dir, nil)
+	test.ErrorNil(t, err)
+
+	test.Equals(t, []string{
+		"/suite/setup",
+		"/a/setup", "/login", "/a/test", "/logout", "/a/teardown",
+		"/fail/setup", "/b/teardown",
+		"/suite/teardown",
+	}, paths())
+
+	statuses := map[string]status{}
+	for _, result := range sum.results {
+		statuses[result.label()] = result.status
+	}
+	test.Equals(t, statusPass, statuses["get order"])
+	test.Equals(t, statusFail, statuses["setup broken setup"])
+	test.Equals(t, statusSkip, statuses["never run"])
+	test.Equals(t, statusPass, statuses["teardown still cleaned up"])
+}
+
+func TestRunRequestsFixturesParallel(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"suite.yaml": `
+litmus:
+  setup:
+  - {name: suite setup, method: POST, url: "{{.base}}/suite/setup"}
+  teardown:
+  - {name: suite teardown, method: DELETE, url: "{{.base}}/suite/teardown"}`,
+		"a_test.yaml": `
+litmus:
+  setup:
+  - {name: a setup, method: POST, url: "{{.base}}/a/setup"}
+  test:
+  - {name: a1, method: GET, url: "{{.base}}/a/1"}
+  - {name: a2, method: GET, url: "{{.base}}/a/2"}
+  teardown:
+  - {name: a teardown, method: DELETE, url: "{{.base}}/a/teardown"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeSuite,
+		parallel:      4,
+		parallelScope: parallelScopeTest,
+	}
+	sum, err := r.runRequests(context.Background(), dir, nil)
+	test.ErrorNil(t, err)
+	test.Assert(t, !sum.failed())
+
+	got := paths()
+	test.Equals(t, 6, len(got))
+	test.Equals(t, "/suite/setup", got[0])
+	test.Equals(t, "/a/setup", got[1])
+	test.Equals(t, "/a/teardown", got[4])
+	test.Equals(t, "/suite/teardown", got[5])
+}
+
+func TestPlanTestsUnknownHook(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="get order"
+before=["missing"]`,
+	})
+	defer os.RemoveAll(dir)
+
+	files, err := loadRequests(dir, nil, false)
+	test.ErrorNil(t, err)
+
+	_, _, err = planTests(nil, files, nil)
+	test.Assert(t, err != nil)
+	test.Equals(t, `test "get order" uses unknown request "missing"`, err.Error())
+}
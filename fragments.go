@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// fragmentsDir is the folder, in the configuration folder, holding
+// files of templates that every test file can extend.
+const fragmentsDir = "fragments"
+
+// includeFiles adds the files a test file includes to it. In strict
+// mode, problems are returned as domain.LintErrors.
+func includeFiles(tf *domain.TestFile, strict bool) error {
+	err := tf.Include(func(path string) (*domain.TestFile, error) {
+		inc := &domain.TestFile{Path: path}
+		return inc, unmarshalTestFile(path, inc, strict)
+	})
+	if err != nil && strict {
+		return domain.LintErrors{{Path: tf.Path, Msg: err.Error()}}
+	}
+	return err
+}
+
+// loadFragments loads the files in the fragments folder, which may
+// only define templates.
+func loadFragments(config string, strict bool) (fragments []*domain.TestFile, err error) {
+	dir := filepath.Join(config, fragmentsDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !testFileExts[strings.ToLower(filepath.Ext(path))] {
+			return err
+		}
+
+		tf := &domain.TestFile{Path: path}
+		if err = unmarshalTestFile(path, tf, strict); err == nil {
+			err = includeFiles(tf, strict)
+		}
+		if err != nil {
+			return err
+		}
+		l := tf.Litmus
+		if len(l.Test)+len(l.Setup)+len(l.Teardown)+len(l.Request) > 0 {
+			return domain.LintErrors{{Path: path, Msg: "fragments may only define templates"}}
+		}
+		fragments = append(fragments, tf)
+		return nil
+	})
+	return fragments, err
+}
+
+// resolveFiles fills in the requests in the suite and test files
+// from the templates they extend, then expands the data-driven
+// tests. Problems in every file are returned together as
+// domain.LintErrors.
+func resolveFiles(config string, suite *domain.TestFile, files []domain.TestFile, strict bool) error {
+	fragments, err := loadFragments(config, strict)
+	if err != nil {
+		if _, ok := err.(domain.LintErrors); ok {
+			return err
+		}
+		return errors.Wrap(err, "loading fragments")
+	}
+
+	shared := fragments
+	if suite != nil {
+		shared = append(shared, suite)
+	}
+	templates, err := domain.SharedTemplates(shared...)
+	if err != nil {
+		return domain.LintErrors{{Msg: err.Error()}}
+	}
+
+	var problems domain.LintErrors
+	if suite != nil {
+		if err = suite.Extend(templates); err != nil {
+			problems = append(problems, domain.LintError{Path: suite.Path, Msg: err.Error()})
+		}
+	}
+	for i := range files {
+		tf := &files[i]
+		if err = tf.Extend(templates); err == nil {
+			err = tf.Expand()
+		}
+		if err != nil {
+			problems = append(problems, domain.LintError{Path: tf.Path, Msg: err.Error()})
+		}
+	}
+	return problems.ErrOrNil()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestRunRequestsTemplates(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"fragments/auth.toml": `
+[litmus.templates.login]
+method="POST"
+url="{{.base}}/login"
+wants_code=200`,
+		"suite.toml": `
+[litmus.templates.admin]
+extends="login"
+url="{{.base}}/login/admin"`,
+		"shared/orders.toml": `
+[[litmus.setup]]
+name="create order"
+method="POST"
+url="{{.base}}/orders"`,
+		"a_test.toml": `
+[litmus]
+include=["shared/orders.toml"]
+
+[litmus.templates.order]
+method="GET"
+url="{{.base}}/orders/1"
+wants_code=200
+
+[[litmus.test]]
+name="log in"
+extends="login"
+
+[[litmus.test]]
+name="log in as admin"
+extends="admin"
+
+[[litmus.test]]
+name="get order"
+extends="order"`,
+		"b_test.yaml": `
+litmus:
+  test:
+  - name: get other order
+    extends: login
+    url: "{{.base}}/orders/2"`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeSuite,
+		parallel:      1,
+		parallelScope: parallelScopeFile,
+		strict:        true,
+	}
+	sum, err := r.runRequests(context.Background(), dir, nil)
+	test.ErrorNil(t, err)
+	test.Assert(t, !sum.failed())
+
+	test.Equals(t, []string{
+		"/orders",
+		"/login", "/login/admin", "/orders/1",
+		"/orders/2",
+	}, paths())
+}
+
+func TestResolveFilesErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		exp   string
+	}{
+		{
+			name: "unknown template",
+			files: map[string]string{"a_test.toml": `
+[[litmus.test]]
+name="get"
+url="http://x"
+extends="missing"`},
+			exp: `a_test.toml: test "get": extends unknown template "missing"`,
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"a_test.toml": `
+[litmus]
+include=["shared.toml"]`,
+				"shared.toml": `
+[litmus]
+include=["a_test.toml"]`,
+			},
+			exp: `a_test.toml: include cycle: `,
+		},
+		{
+			name: "fragment with tests",
+			files: map[string]string{
+				"a_test.toml": `
+[[litmus.test]]
+name="get"
+url="http://x"`,
+				"fragments/login.toml": `
+[[litmus.test]]
+name="log in"`,
+			},
+			exp: `fragments/login.toml: fragments may only define templates`,
+		},
+		{
+			name: "unknown field in template",
+			files: map[string]string{"a_test.toml": `
+[litmus.templates.login]
+url="http://x"
+want_code=200
+[litmus.templates.login.body]
+token = {token = "abc"}`},
+			exp: `a_test.toml:4: "login": unknown field "want_code"`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := writeFiles(t, c.files)
+			defer os.RemoveAll(dir)
+
+			problems, err := lint(dir, nil, nil)
+			test.ErrorNil(t, err)
+			test.Equals(t, 1, len(problems))
+			test.Assert(t, strings.HasPrefix(strings.TrimPrefix(problems[0].Error(), dir+"/"), c.exp))
+		})
+	}
+}
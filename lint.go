@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/spf13/cobra"
+)
+
+// lintCommand returns the command that checks the test files for
+// problems without running them.
+func lintCommand() *cobra.Command {
+	var configPath string
+	var targetEnvs []string
+	var eVariables domain.KeyValuePairs
+	var includePatterns []string
+	var ignorePatterns []string
+
+	cmd := &cobra.Command{
+		Use:     "lint [paths...]",
+		Aliases: []string{"validate"},
+		Short:   lintShort,
+		Long:    lintLong,
+		Run: func(cmd *cobra.Command, args []string) {
+			env, err := loadEnv(configPath, targetEnvs, eVariables)
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+
+			problems, err := lint(configPath, newFinder(args, includePatterns, ignorePatterns), env.values)
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+
+			printProblems(os.Stdout, problems)
+			if len(problems) > 0 {
+				os.Exit(exitFailure)
+			}
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "config", "c", "", cFlagUsage)
+	cmd.Flags().StringArrayVarP(&targetEnvs, "using", "u", nil, uFlagUsage)
+	cmd.Flags().VarP(&eVariables, "env", "e", eFlagUsage)
+	cmd.Flags().StringArrayVar(&includePatterns, "include", nil, includeFlagUsage)
+	cmd.Flags().StringArrayVar(&ignorePatterns, "ignore", nil, ignoreFlagUsage)
+	cmd.MarkFlagRequired("config")
+
+	return cmd
+}
+
+// lint checks the test files found by f in config, returning every
+// problem found. An error is only returned if the files couldn't be
+// read.
+func lint(config string, f *finder, env map[string]interface{}) (problems domain.LintErrors, err error) {
+	domain.SetTemplateRoot(config)
+	domain.SetStrictTemplates(true)
+
+	files, err := loadRequests(config, f, true)
+	if problems, err = addProblems(problems, err); err != nil {
+		return nil, err
+	}
+	suite, err := loadSuite(config, true)
+	if problems, err = addProblems(problems, err); err != nil {
+		return nil, err
+	}
+
+	problems, err = addProblems(problems, resolveFiles(config, suite, files, true))
+	if err != nil {
+		return nil, err
+	}
+	applyDefaults(suite, files)
+	problems = append(problems, validateFiles(suite, files)...)
+
+	// The checks made before a run need every file to have loaded.
+	if len(problems) > 0 {
+		return problems, nil
+	}
+	tests, _, err := planTests(suite, files, nil)
+	if err == nil {
+		err = linkDependencies(tests, parallelScopeTest)
+	}
+	if err == nil {
+		err = preflight(env, tests)
+	}
+	if err != nil {
+		problems = append(problems, domain.LintError{Msg: err.Error()})
+	}
+	return problems, nil
+}
+
+// addProblems appends the problems in err, if it holds any, to
+// problems. Any other error is returned.
+func addProblems(problems domain.LintErrors, err error) (domain.LintErrors, error) {
+	if errs, ok := err.(domain.LintErrors); ok {
+		return append(problems, errs...), nil
+	}
+	return problems, err
+}
+
+// validateFiles checks the requests in each file, and that no two
+// tests or shared requests have the same name.
+func validateFiles(suite *domain.TestFile, files []domain.TestFile) (problems domain.LintErrors) {
+	all := files
+	if suite != nil {
+		all = append([]domain.TestFile{*suite}, files...)
+	}
+
+	seen := make(map[string]map[string]string)
+	for _, tf := range all {
+		if errs, ok := tf.Validate().(domain.LintErrors); ok {
+			problems = append(problems, errs...)
+		}
+
+		for _, section := range []string{domain.SectionTest, domain.SectionRequest} {
+			if seen[section] == nil {
+				seen[section] = make(map[string]string)
+			}
+			for _, r := range tf.Litmus.Section(section) {
+				if r.Name == "" {
+					continue
+				}
+				if first, ok := seen[section][r.Name]; ok {
+					problems = append(problems, domain.LintError{
+						Path: tf.Path,
+						Test: r.Name,
+						Msg:  fmt.Sprintf("duplicate %s name, first used in %s", section, first),
+					})
+					continue
+				}
+				seen[section][r.Name] = tf.Path
+			}
+		}
+	}
+	return problems
+}
+
+// printProblems writes each problem found by lint, followed by a
+// count.
+func printProblems(w io.Writer, problems domain.LintErrors) {
+	for _, p := range problems {
+		fmt.Fprintln(w, red(p.Error()))
+	}
+	switch len(problems) {
+	case 0:
+		fmt.Fprintln(w, green("no problems found"))
+	case 1:
+		fmt.Fprintln(w, red("1 problem"))
+	default:
+		fmt.Fprintln(w, red(fmt.Sprintf("%d problems", len(problems))))
+	}
+}
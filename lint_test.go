@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestLint(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="create order"
+method="POST"
+url="{{.base}}/orders"
+want_code=201
+body='''{"id": 1}'''`,
+		"b_test.yaml": `
+litmus:
+  test:
+  - name: get order
+    method: FETCH
+    url: "{{.base}}/orders/1"
+  - name: get order
+    url: "{{.base}}/orders/2"`,
+	})
+	defer os.RemoveAll(dir)
+
+	problems, err := lint(dir, nil, map[string]interface{}{"base": "http://x"})
+	test.ErrorNil(t, err)
+
+	var lines []string
+	for _, p := range problems {
+		lines = append(lines, strings.TrimPrefix(p.Error(), dir+"/"))
+	}
+	test.Equals(t, []string{
+		`a_test.toml:6: "create order": unknown field "want_code"`,
+		`a_test.toml:7: "create order": body must be a table, got string`,
+		`b_test.yaml: "get order": invalid method "FETCH"`,
+		`b_test.yaml: "get order": duplicate test name, first used in ` + dir + `/b_test.yaml`,
+	}, lines)
+
+	buf := &bytes.Buffer{}
+	printProblems(buf, problems)
+	test.Assert(t, strings.HasSuffix(buf.String(), "4 problems\n"))
+}
+
+func TestLintPreflight(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="get order"
+url="{{.base}}/orders/{{.id}}"
+depends_on=["create order"]`,
+	})
+	defer os.RemoveAll(dir)
+
+	problems, err := lint(dir, nil, map[string]interface{}{"base": "http://x"})
+	test.ErrorNil(t, err)
+	test.Equals(t, 1, len(problems))
+	test.Equals(t, `test "get order" depends on unknown test "create order"`, problems[0].Error())
+
+	dir2 := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="get order"
+url="{{.base}}/orders/{{.id}}"`,
+	})
+	defer os.RemoveAll(dir2)
+
+	problems, err = lint(dir2, nil, map[string]interface{}{"base": "http://x"})
+	test.ErrorNil(t, err)
+	test.Equals(t, 1, len(problems))
+	test.Assert(t, strings.Contains(problems[0].Error(), `uses undefined variable "id"`))
+}
+
+func TestRunRequestsStrict(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="get order"
+url="http://x"
+want_code=200`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{jarScope: jarScopeSuite, parallelScope: parallelScopeFile, strict: true}
+	_, err := r.runRequests(nil, dir, nil)
+	test.Assert(t, err != nil)
+	test.Equals(t, dir+`/a_test.toml:5: "get order": unknown field "want_code"`, err.Error())
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
+	"net/http/cookiejar"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -19,14 +27,50 @@ import (
 )
 
 var (
-	red   = color.New(color.FgHiRed).SprintFunc()
-	green = color.New(color.FgHiGreen).SprintFunc()
-	blue  = color.New(color.FgHiBlue).SprintFunc()
+	red    = color.New(color.FgHiRed).SprintFunc()
+	green  = color.New(color.FgHiGreen).SprintFunc()
+	blue   = color.New(color.FgHiBlue).SprintFunc()
+	yellow = color.New(color.FgHiYellow).SprintFunc()
+)
+
+// Scopes of the cookie jar shared between tests.
+const (
+	jarScopeSuite = "suite"
+	jarScopeFile  = "file"
+	jarScopeNone  = "none"
+)
+
+// Units of parallelism when tests run concurrently.
+const (
+	parallelScopeFile = "file"
+	parallelScopeTest = "test"
 )
 
 type runner struct {
-	client *http.Client
-	env    map[string]interface{}
+	client        *http.Client
+	env           map[string]interface{}
+	failFast      bool
+	verbose       bool
+	bodyLimit     int
+	jarScope      string
+	parallel      int
+	parallelScope string
+
+	// laxTemplates allows templates to reference keys that
+	// aren't in the environment.
+	laxTemplates bool
+
+	// finder discovers the test files to load.
+	finder *finder
+
+	// strict rejects test files with unknown keys or invalid
+	// requests before anything is run.
+	strict bool
+
+	// mu guards env and jars, which are shared by tests
+	// running in parallel.
+	mu   sync.RWMutex
+	jars map[string]http.CookieJar
 }
 
 func main() {
@@ -37,24 +81,38 @@ func main() {
 	var timeoutLen int
 	var configPath string
 	var testByName string
-	var targetEnv string
+	var targetEnvs []string
+	var failFast bool
+	var verbose bool
+	var bodyLimit int
+	var jarScope string
+	var parallel int
+	var parallelScope string
+	var deadline time.Duration
+	var runPatterns []string
+	var excludePatterns []string
+	var filePatterns []string
+	var tagExpression string
+	var withDeps bool
+	var laxTemplates bool
+	var strict bool
+	var includePatterns []string
+	var ignorePatterns []string
 	var eVariables domain.KeyValuePairs
 
 	rootCmd := cobra.Command{
-		Use:   "litmus",
+		Use:   "litmus [paths...]",
 		Short: "Run automated HTTP requests.",
 		Long:  litmusBanner + longHelp,
+		Args:  cobra.ArbitraryArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			// pick the env.toml and unmarshal it into a map
-			env, err := setEnvironmentFile(configPath, targetEnv)
-			if err != nil {
-				log.Fatal(err)
+			for _, file := range targetEnvs {
+				fmt.Println(green("Running tests using: ", filepath.Base(file)))
 			}
-
-			// Set environment from user args, taking precedence
-			// over the environment config in env.toml.
-			for _, kvp := range eVariables {
-				env[kvp.Key] = kvp.Value
+			env, err := loadEnv(configPath, targetEnvs, eVariables)
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
 			}
 
 			// Ensure timeout is checked, if provided by the user
@@ -64,12 +122,41 @@ func main() {
 			}
 
 			runner := runner{
-				client: client,
-				env:    env,
+				client:        client,
+				env:           env.values,
+				failFast:      failFast,
+				verbose:       verbose,
+				bodyLimit:     bodyLimit,
+				jarScope:      jarScope,
+				parallel:      parallel,
+				parallelScope: parallelScope,
+				laxTemplates:  laxTemplates,
+				strict:        strict,
+				finder:        newFinder(args, includePatterns, ignorePatterns),
 			}
 
-			if err := runner.runRequests(configPath, testByName); err != nil {
-				fmt.Printf("\t[%s] %v\n", red("FAIL"), err)
+			sel, err := newSelector(configPath, testByName, runPatterns, excludePatterns, filePatterns, tagExpression)
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+			sel.withDeps = withDeps
+
+			ctx, cancel := runContext(deadline)
+			defer cancel()
+
+			sum, err := runner.runRequests(ctx, configPath, sel)
+			if err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+
+			sum.print(os.Stdout)
+			switch {
+			case sum.stopped != nil:
+				os.Exit(exitError)
+			case sum.failed():
+				os.Exit(exitFailure)
 			}
 		},
 	}
@@ -77,133 +164,508 @@ func main() {
 	rootCmd.Flags().StringVarP(&configPath, "config", "c", "", cFlagUsage)
 	rootCmd.Flags().StringVarP(&testByName, "test", "n", "", nFlagUsage)
 	rootCmd.Flags().IntVarP(&timeoutLen, "timeout", "t", 0, tFlagUsage)
-	rootCmd.Flags().StringVarP(&targetEnv, "using", "u", "", uFlagUsage)
+	rootCmd.Flags().StringArrayVarP(&targetEnvs, "using", "u", nil, uFlagUsage)
 	rootCmd.Flags().VarP(&eVariables, "env", "e", eFlagUsage)
+	rootCmd.Flags().BoolVar(&failFast, "fail-fast", false, failFastFlagUsage)
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, vFlagUsage)
+	rootCmd.Flags().IntVar(&bodyLimit, "body-limit", 2048, bodyLimitFlagUsage)
+	rootCmd.Flags().StringVar(&jarScope, "cookie-jar", jarScopeSuite, cookieJarFlagUsage)
+	rootCmd.Flags().IntVarP(&parallel, "parallel", "p", 1, parallelFlagUsage)
+	rootCmd.Flags().StringVar(&parallelScope, "parallel-scope", parallelScopeFile, parallelScopeFlagUsage)
+	rootCmd.Flags().DurationVar(&deadline, "deadline", 0, deadlineFlagUsage)
+	rootCmd.Flags().StringArrayVar(&runPatterns, "run", nil, runFlagUsage)
+	rootCmd.Flags().StringArrayVar(&excludePatterns, "exclude", nil, excludeFlagUsage)
+	rootCmd.Flags().StringArrayVar(&filePatterns, "file", nil, fileFlagUsage)
+	rootCmd.Flags().StringVar(&tagExpression, "tags", "", tagsFlagUsage)
+	rootCmd.Flags().BoolVar(&withDeps, "with-deps", false, withDepsFlagUsage)
+	rootCmd.Flags().BoolVar(&laxTemplates, "lax-templates", false, laxTemplatesFlagUsage)
+	rootCmd.Flags().BoolVar(&strict, "strict", false, strictFlagUsage)
+	rootCmd.Flags().StringArrayVar(&includePatterns, "include", nil, includeFlagUsage)
+	rootCmd.Flags().StringArrayVar(&ignorePatterns, "ignore", nil, ignoreFlagUsage)
 
 	// enforce the required flags
 	rootCmd.MarkFlagRequired("config")
 
+	rootCmd.AddCommand(lintCommand(), schemaCommand(), envCommand())
+
+	// Cobra has already printed the error, such as a missing
+	// required flag, along with the usage.
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+		os.Exit(exitError)
 	}
 }
 
-func (r *runner) runRequests(config string, name string) (err error) {
-	litmusFiles, err := loadRequests(config)
+// runContext returns the context a run is performed in. It's
+// cancelled on SIGINT or SIGTERM, or once deadline has passed
+// if it's non-zero.
+func runContext(deadline time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, deadline)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
+
+// runRequests executes every selected test and returns a summary of
+// the results. An error is only returned if the tests could not be
+// loaded; test failures are recorded in the summary.
+func (r *runner) runRequests(ctx context.Context, config string, sel *selector) (sum *summary, err error) {
+	switch r.jarScope {
+	case jarScopeSuite, jarScopeFile, jarScopeNone:
+	default:
+		return nil, errors.Errorf("invalid cookie jar scope %q", r.jarScope)
+	}
+	switch r.parallelScope {
+	case parallelScopeFile, parallelScopeTest:
+	default:
+		return nil, errors.Errorf("invalid parallel scope %q", r.parallelScope)
+	}
+
+	domain.SetTemplateRoot(config)
+	domain.SetStrictTemplates(!r.laxTemplates)
+	litmusFiles, err := loadRequests(config, r.finder, r.strict)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	suite, err := loadSuite(config, r.strict)
+	if err != nil {
+		return nil, err
+	}
+	if err = resolveFiles(config, suite, litmusFiles, r.strict); err != nil {
+		return nil, err
+	}
+	applyDefaults(suite, litmusFiles)
+	if r.strict {
+		if err = validateFiles(suite, litmusFiles).ErrOrNil(); err != nil {
+			return nil, err
+		}
 	}
 
-	for _, file := range litmusFiles {
-		for _, test := range file.Litmus.Test {
-			if name != "" && test.Name != name {
-				continue
-			}
+	tests, skipped, err := planTests(suite, litmusFiles, sel)
+	if err != nil {
+		return nil, err
+	}
 
-			if err = r.runRequest(&test); err != nil {
-				return
-			}
+	if err = linkDependencies(tests, r.parallelScope); err != nil {
+		return nil, err
+	}
+	if !r.laxTemplates {
+		if err = preflight(r.env, tests); err != nil {
+			return nil, err
 		}
 	}
 
+	sum = &summary{}
+	start := time.Now()
+	defer func() {
+		for _, result := range skipped {
+			sum.add(result)
+		}
+		sum.duration = time.Since(start)
+		sum.stopped = ctx.Err()
+	}()
+
+	if r.parallel > 1 {
+		r.runParallel(ctx, tests, sum)
+		return
+	}
+
+	results := make([]testResult, len(tests))
+	stop := false
+	for _, i := range serialOrder(tests) {
+		results[i] = r.runPlanned(ctx, os.Stdout, tests, results, i, stop)
+		if results[i].status == statusFail && r.failFast && tests[i].phase != phaseTeardown {
+			stop = true
+		}
+	}
+	for _, result := range results {
+		sum.add(result)
+	}
+
 	return
 }
 
-func loadRequests(config string) (tests []domain.TestFile, err error) {
-	files, err := glob(config, "*_test.toml", "*_test.yaml")
+// runPlanned runs the planned test at index i, or skips it if the
+// run has been stopped or a setup request it relies on didn't pass.
+// Teardown requests are always run, even once the run has been
+// interrupted.
+func (r *runner) runPlanned(ctx context.Context, w io.Writer, tests []*plannedTest, results []testResult, i int, stopped bool) testResult {
+	t := tests[i]
+	switch {
+	case t.phase == phaseTeardown:
+		return r.runTest(context.Background(), w, t)
+	case stopped || ctx.Err() != nil:
+		return testResult{name: t.test.Name, file: t.file, phase: t.phase, status: statusSkip}
+	case blocked(tests, results, t):
+		return testResult{name: t.test.Name, file: t.file, phase: t.phase, status: statusSkip, reason: reasonSetupFailed}
+	}
+	return r.runTest(ctx, w, t)
+}
+
+// runTest runs a single test and its hooks, writing its output to w.
+func (r *runner) runTest(ctx context.Context, w io.Writer, t *plannedTest) testResult {
+	result := testResult{name: t.test.Name, file: t.file, phase: t.phase}
+
+	start := time.Now()
+	result.err = r.runHooks(ctx, w, t.file, "before", t.before)
+	if result.err == nil {
+		result.err = r.runRequest(ctx, w, t.file, &t.test)
+	}
+	if err := r.runHooks(ctx, w, t.file, "after", t.after); err != nil && result.err == nil {
+		result.err = err
+	}
+	result.duration = time.Since(start)
+
+	if result.err != nil {
+		result.status = statusFail
+	}
+	return result
+}
+
+// runHooks makes a test's before or after requests in order,
+// stopping at the first that fails.
+func (r *runner) runHooks(ctx context.Context, w io.Writer, file, kind string, hooks []domain.RequestTest) error {
+	for _, hook := range hooks {
+		hook := hook
+		if err := r.runRequest(ctx, w, file, &hook); err != nil {
+			return errors.Wrapf(err, "%s request %q", kind, hook.Name)
+		}
+	}
+	return nil
+}
+
+// loadRequests loads the test files found by f in config. In strict mode,
+// the problems found in every file are returned together as
+// domain.LintErrors, along with the files that loaded cleanly.
+func loadRequests(config string, f *finder, strict bool) (tests []domain.TestFile, err error) {
+	files, err := f.find(config)
 	if err != nil {
-		log.Fatalf("error globbing files: %v", err)
+		return nil, errors.Wrap(err, "finding test files")
 	}
 	if len(files) == 0 {
-		log.Fatalf("no test files found in %s folder", config)
+		return nil, errors.Errorf("no test files found in %s folder", config)
 	}
 
+	var problems domain.LintErrors
 	for _, file := range files {
-		var lit domain.TestFile
-		if err = unmarhsal(file, &lit); err != nil {
-			return
+		lit := domain.TestFile{Path: file}
+		err = unmarshalTestFile(file, &lit, strict)
+		if err == nil {
+			err = includeFiles(&lit, strict)
+		}
+		if err != nil {
+			if errs, ok := err.(domain.LintErrors); ok {
+				problems = append(problems, errs...)
+				continue
+			}
+			return nil, errors.Wrapf(err, "loading %s", file)
 		}
 
 		tests = append(tests, lit)
 	}
-	return
+	return tests, problems.ErrOrNil()
 }
 
-func glob(root string, patterns ...string) (paths []string, err error) {
-	root = strings.TrimSuffix(root, "/") + "/"
-
-	for _, pattern := range patterns {
-		files, err := filepath.Glob(root + pattern)
+// runRequest performs a test's request and checks its response,
+// writing the outcome to w. The test works on its own copy of
+// the environment, and the values it captures are merged back
+// into the shared environment once it's complete.
+func (r *runner) runRequest(ctx context.Context, w io.Writer, file string, req *domain.RequestTest) (err error) {
+	ex := &exchange{}
+	attempts := 0
+	defer func() {
 		if err != nil {
-			return nil, err
+			fmt.Fprintf(w, "\t[%s] %v\n", red("FAIL"), err)
+		} else if attempts > 1 {
+			fmt.Fprintf(w, "\t[%s] after %d attempts\n", green("PASS"), attempts)
+		} else {
+			fmt.Fprintf(w, "\t[%s]\n", green("PASS"))
 		}
-		paths = append(paths, files...)
-	}
+		if err != nil || r.verbose {
+			ex.write(w, r.bodyLimit)
+		}
+	}()
 
-	return
-}
+	if err := req.Retry.Validate(); err != nil {
+		return errors.Wrap(err, "invalid retry policy")
+	}
+	if err := req.Until.Validate(); err != nil {
+		return errors.Wrap(err, "invalid until policy")
+	}
 
-func (r *runner) runRequest(req *domain.RequestTest) (err error) {
-	if err := req.ApplyEnv(r.env); err != nil {
+	env := r.snapshotEnv()
+	for k, v := range req.Vars {
+		env[k] = v
+	}
+	if err := req.ApplyEnv(env); err != nil {
 		return errors.Wrap(err, "applying environment")
 	}
 
-	fmt.Printf("[%s] %s - %s\n", blue("TEST"), req.Name, req.URL)
+	fmt.Fprintf(w, "[%s] %s - %s\n", blue("TEST"), req.Name, req.URL)
+
+	if err := sleep(ctx, time.Duration(req.DelayBefore)); err != nil {
+		return errors.Wrap(err, "waiting before request")
+	}
+	defer func() {
+		if serr := sleep(ctx, time.Duration(req.DelayAfter)); serr != nil && err == nil {
+			err = errors.Wrap(serr, "waiting after request")
+		}
+	}()
+
+	testCtx := ctx
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		testCtx, cancel = context.WithTimeout(ctx, time.Duration(req.Timeout))
+		defer cancel()
+	}
 
-	request, err := http.NewRequest(req.Method, req.URL, strings.NewReader(req.Payload))
+	if req.ClearCookies {
+		r.resetJar(file)
+	}
+	client := *r.client
+	client.Jar = r.jarFor(file)
+	if req.Timeout > 0 {
+		// The test's timeout replaces the -t timeout, so it can
+		// be longer as well as shorter.
+		client.Timeout = 0
+	}
+
+	// Repeat the request until its assertions pass, or the until
+	// policy gives up, reporting the last failure.
+	start := time.Now()
+	for attempts = 1; ; attempts++ {
+		var request *http.Request
+		if request, err = newRequest(testCtx, req, client.Jar); err != nil {
+			return errors.Wrap(err, "creating request")
+		}
+		*ex = exchange{request: request, payload: req.Payload}
+
+		err = r.exchange(testCtx, &client, req, ex, env)
+		if err == nil || !retry(testCtx, req.Until, attempts, start) {
+			break
+		}
+	}
+	r.mergeEnv(env, req.SetKeys())
+
+	if err != nil && attempts > 1 {
+		return errors.Wrapf(err, "after %d attempts", attempts)
+	}
+	return err
+}
+
+// newRequest builds the HTTP request for a test.
+func newRequest(ctx context.Context, req *domain.RequestTest, jar http.CookieJar) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, req.Method, req.URL, strings.NewReader(req.Payload))
 	if err != nil {
-		return errors.Wrap(err, "creating request")
+		return nil, err
 	}
 
 	for k, v := range req.Headers {
 		request.Header.Set(k, v)
 	}
+	if auth := req.Auth.Header(); auth != "" && request.Header.Get("Authorization") == "" {
+		request.Header.Set("Authorization", auth)
+	}
+	for _, k := range req.UnsetHeaders {
+		request.Header.Del(k)
+	}
 
 	q := request.URL.Query()
 	for k, v := range req.Query {
 		q.Add(k, v)
 	}
 	request.URL.RawQuery = q.Encode()
+	seedCookies(jar, request, req.Cookies)
+	return request, nil
+}
 
-	resp, err := r.client.Do(request)
-	if err != nil {
-		return errors.Wrap(err, "performing request")
+// exchange sends the request held by ex, retrying it according to
+// the test's retry policy if no response is received, and checks
+// the response against the test's assertions.
+func (r *runner) exchange(ctx context.Context, client *http.Client, req *domain.RequestTest, ex *exchange, env map[string]interface{}) error {
+	start := time.Now()
+	var resp *http.Response
+	for attempt := 1; ; attempt++ {
+		var err error
+		if resp, err = client.Do(ex.request); err == nil {
+			break
+		}
+		if !retry(ctx, req.Retry, attempt, start) {
+			return errors.Wrap(err, "performing request")
+		}
+		if ex.request.GetBody != nil {
+			if ex.request.Body, err = ex.request.GetBody(); err != nil {
+				return errors.Wrap(err, "rewinding request body")
+			}
+		}
 	}
 	defer resp.Body.Close()
 
+	// Buffer the body so it can be displayed after it has
+	// been consumed by the assertions.
+	var err error
+	if ex.body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return errors.Wrap(err, "reading response body")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(ex.body))
+	ex.response = resp
+
 	// Get, set and assert stuff from the response body.
-	if err = domain.ProcessResponse(req, resp, r.env); err != nil {
-		return errors.Wrap(err, "extracting body")
+	if err = domain.ProcessResponse(req, resp, env); err != nil {
+		return errors.Wrap(err, "processing response")
 	}
+	return nil
+}
 
-	fmt.Printf("\t[%s]\n", green("PASS"))
-	return
+// retry waits before the next attempt at a request and reports
+// whether it should be made, according to policy. start is when
+// the first attempt was made.
+func retry(ctx context.Context, policy *domain.RetryPolicy, attempt int, start time.Time) bool {
+	if ctx.Err() != nil || !policy.Allows(attempt+1) {
+		return false
+	}
+
+	wait := policy.Wait(attempt, rand.Float64())
+	if policy.Deadline > 0 && time.Since(start)+wait > time.Duration(policy.Deadline) {
+		return false
+	}
+
+	return sleep(ctx, wait) == nil
 }
 
-func setEnvironmentFile(config string, targetEnv string) (env map[string]interface{}, err error) {
-	const envFile = "env.toml"
-	var fullPath string
+// sleep pauses for d, returning early with the context's error
+// if it's done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
 
-	// default path
-	fullPath = filepath.Join(config, envFile)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	if targetEnv != "" {
-		// if not using default, warn
-		fmt.Println(green("Running tests using: ", filepath.Base(targetEnv)))
-		fullPath = targetEnv
+// snapshotEnv returns a copy of the shared environment.
+func (r *runner) snapshotEnv() map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	env := make(map[string]interface{}, len(r.env))
+	for k, v := range r.env {
+		env[k] = v
 	}
+	return env
+}
 
-	// If the file doesn't exist, nil out the error because
-	// this isn't a show-stopper.
-	if _, err := os.Stat(fullPath); err != nil {
-		log.Printf("env file %q does not exist", fullPath)
-		return nil, err
+// mergeEnv copies the given keys from a test's environment into
+// the shared environment.
+func (r *runner) mergeEnv(env map[string]interface{}, keys []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range keys {
+		if v, ok := env[k]; ok {
+			r.env[k] = v
+		}
 	}
+}
 
-	if err := unmarhsal(fullPath, &env); err != nil {
-		return nil, err
+// jarKey returns the key of the cookie jar used by tests in file.
+func (r *runner) jarKey(file string) string {
+	if r.jarScope == jarScopeFile {
+		return file
 	}
-	return env, nil
+	return ""
+}
+
+// jarFor returns the cookie jar used by tests in file, creating
+// it if needed, or nil if cookies aren't being kept.
+func (r *runner) jarFor(file string) http.CookieJar {
+	if r.jarScope == jarScopeNone {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jars == nil {
+		r.jars = make(map[string]http.CookieJar)
+	}
+	key := r.jarKey(file)
+	jar, ok := r.jars[key]
+	if !ok {
+		// cookiejar.New only returns an error for invalid options.
+		jar, _ = cookiejar.New(nil)
+		r.jars[key] = jar
+	}
+	return jar
+}
+
+// resetJar discards the cookie jar used by tests in file, so the
+// next test starts with an empty one.
+func (r *runner) resetJar(file string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.jars, r.jarKey(file))
+}
+
+// seedCookies adds the test's cookies to the jar, so they're sent
+// with this request and any that follow it, or directly to the
+// request if there's no jar.
+func seedCookies(jar http.CookieJar, request *http.Request, cookies map[string]string) {
+	for name, value := range cookies {
+		// Without a path, the jar would only send the cookie to
+		// URLs in the request's folder.
+		c := &http.Cookie{Name: name, Value: value, Path: "/"}
+		if jar == nil {
+			request.AddCookie(c)
+			continue
+		}
+		jar.SetCookies(request.URL, []*http.Cookie{c})
+	}
+}
+
+// unmarshalTestFile decodes a test file, preserving the order of
+// its assertions. In strict mode, unknown keys are rejected.
+func unmarshalTestFile(fullPath string, tf *domain.TestFile, strict bool) (err error) {
+	file, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return errors.Wrap(err, "reading file")
+	}
+	switch strings.ToLower(filepath.Ext(fullPath)) {
+	case ".toml":
+		if strict {
+			return domain.UnmarshalTOMLStrict(file, tf)
+		}
+		return domain.UnmarshalTOML(file, tf)
+	case ".yaml", ".yml", ".json":
+		// JSON is decoded as YAML, which it's a subset of, to
+		// keep the order of its assertions.
+		if strict {
+			return domain.UnmarshalYAMLStrict(file, tf)
+		}
+		return domain.UnmarshalYAML(file, tf)
+	}
+
+	return
 }
 
 func unmarhsal(fullPath string, target interface{}) (err error) {
@@ -216,10 +678,12 @@ func unmarhsal(fullPath string, target interface{}) (err error) {
 		if err = toml.Unmarshal(file, target); err != nil {
 			return errors.Wrap(err, "unmarshalling")
 		}
-	case ".yaml":
+	case ".yaml", ".yml", ".json":
 		if err = yaml.Unmarshal(file, target); err != nil {
 			return errors.Wrap(err, "unmarshalling")
 		}
+	default:
+		return errors.Errorf("unsupported file type %q, expected .toml, .yaml, .yml or .json", filepath.Ext(fullPath))
 	}
 
 	return
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// preflight checks, before any request is made, that every key the
+// planned tests' templates rely on will be available when it's
+// needed: from the environment, from the test's own vars, or
+// captured by a test, or hook, that runs earlier. Keys captured by
+// a test are available to its own assertions.
+func preflight(env map[string]interface{}, tests []*plannedTest) error {
+	known := make(map[string]bool, len(env))
+	for k := range env {
+		known[k] = true
+	}
+
+	// capturedBy records the first test capturing each key, to
+	// explain keys that are used before they're captured.
+	capturedBy := make(map[string]string)
+	note := func(req *domain.RequestTest) {
+		for _, k := range req.SetKeys() {
+			if _, ok := capturedBy[k]; !ok {
+				capturedBy[k] = req.Name
+			}
+		}
+	}
+	for _, t := range tests {
+		for i := range t.before {
+			note(&t.before[i])
+		}
+		note(&t.test)
+		for i := range t.after {
+			note(&t.after[i])
+		}
+	}
+
+	var problems []string
+	check := func(t *plannedTest, req *domain.RequestTest) {
+		report := func(k string) {
+			name := fmt.Sprintf("%q", req.Name)
+			if req.Name != t.test.Name {
+				name = fmt.Sprintf("%q (used by %q)", req.Name, t.test.Name)
+			}
+			if producer, ok := capturedBy[k]; ok {
+				problems = append(problems, fmt.Sprintf("%s: %s uses %q before it's captured by %q", t.file, name, k, producer))
+				return
+			}
+			problems = append(problems, fmt.Sprintf("%s: %s uses undefined variable %q", t.file, name, k))
+		}
+
+		for _, k := range req.RequestRefs() {
+			if !known[k] {
+				report(k)
+			}
+		}
+		own := req.SetKeys()
+		for _, k := range own {
+			known[k] = true
+		}
+		for _, k := range req.AssertionRefs() {
+			if !known[k] {
+				report(k)
+			}
+		}
+	}
+
+	for _, t := range tests {
+		for i := range t.before {
+			check(t, &t.before[i])
+		}
+		check(t, &t.test)
+		for i := range t.after {
+			check(t, &t.after[i])
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("undefined template variables:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
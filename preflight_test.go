@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestPreflight(t *testing.T) {
+	login := domain.RequestTest{
+		Name: "login",
+		URL:  "{{.base_url}}/login",
+		Body: map[string]interface{}{"token": map[string]interface{}{"token": "abc"}},
+	}
+
+	tests := []struct {
+		name  string
+		tests []*plannedTest
+		err   string
+	}{
+		{
+			name: "captured earlier",
+			tests: []*plannedTest{
+				{file: "a", test: login},
+				{file: "a", test: domain.RequestTest{Name: "me", URL: "{{.base_url}}/me", Headers: map[string]string{"Authorization": "{{.token}}"}}},
+			},
+		},
+		{
+			name: "captured by own assertions",
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{
+					Name: "create",
+					URL:  "{{.base_url}}/orders",
+					Body: map[string]interface{}{
+						"order_id": map[string]interface{}{"id": "1"},
+						"self":     "/orders/{{.order_id}}",
+					},
+				}},
+			},
+		},
+		{
+			name: "vars, hooks and optional keys",
+			tests: []*plannedTest{
+				{
+					file:   "a",
+					test:   domain.RequestTest{Name: "get", URL: "{{.base_url}}/{{.id}}?q={{.q | default \"\"}}", Headers: map[string]string{"Authorization": "{{.token}}"}, Vars: map[string]interface{}{"id": 1}},
+					before: []domain.RequestTest{login},
+				},
+			},
+		},
+		{
+			name: "undefined",
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "get", URL: "{{.base_servce_url}}/get"}},
+			},
+			err: "undefined template variables:\n\ta: \"get\" uses undefined variable \"base_servce_url\"",
+		},
+		{
+			name: "used before captured",
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "me", URL: "{{.base_url}}/me", Headers: map[string]string{"Authorization": "{{.token}}"}}},
+				{file: "b", test: login},
+			},
+			err: "undefined template variables:\n\ta: \"me\" uses \"token\" before it's captured by \"login\"",
+		},
+		{
+			name: "hook",
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "get", URL: "{{.base_url}}"}, after: []domain.RequestTest{{Name: "log out", URL: "{{.logout_url}}"}}},
+			},
+			err: "undefined template variables:\n\ta: \"log out\" (used by \"get\") uses undefined variable \"logout_url\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := preflight(map[string]interface{}{"base_url": "http://localhost"}, tt.tests)
+			if tt.err == "" {
+				test.ErrorNil(t, err)
+				return
+			}
+			test.Assert(t, err != nil)
+			test.Equals(t, tt.err, err.Error())
+		})
+	}
+}
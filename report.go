@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Exit codes returned by the litmus process when it doesn't succeed.
+const (
+	exitFailure = 1
+	exitError   = 2
+)
+
+type status int
+
+const (
+	statusPass status = iota
+	statusFail
+	statusSkip
+)
+
+func (s status) String() string {
+	switch s {
+	case statusPass:
+		return green("PASS")
+	case statusFail:
+		return red("FAIL")
+	default:
+		return yellow("SKIP")
+	}
+}
+
+// testResult holds the outcome of a single test.
+type testResult struct {
+	name     string
+	file     string
+	status   status
+	duration time.Duration
+	err      error
+
+	// reason explains why a test was skipped before it was run.
+	reason string
+
+	// phase is set for setup and teardown requests.
+	phase string
+}
+
+// label names the test in the summary.
+func (r testResult) label() string {
+	if r.phase != "" {
+		return r.phase + " " + r.name
+	}
+	return r.name
+}
+
+// summary collects the results of every test in a run.
+type summary struct {
+	results  []testResult
+	duration time.Duration
+
+	// stopped is the reason the run ended before every test had
+	// been run, if it did.
+	stopped error
+}
+
+func (s *summary) add(r testResult) {
+	s.results = append(s.results, r)
+}
+
+// counts returns the number of tests that passed, failed and were
+// skipped. Tests left out on the command line aren't counted as
+// skipped; notSelected counts them.
+func (s *summary) counts() (passed, failed, skipped int) {
+	for _, r := range s.results {
+		if r.reason == reasonNotSelected {
+			continue
+		}
+		switch r.status {
+		case statusPass:
+			passed++
+		case statusFail:
+			failed++
+		case statusSkip:
+			skipped++
+		}
+	}
+	return
+}
+
+// notSelected returns the number of tests left out on the command
+// line.
+func (s *summary) notSelected() (n int) {
+	for _, r := range s.results {
+		if r.reason == reasonNotSelected {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *summary) failed() bool {
+	_, failed, _ := s.counts()
+	return failed > 0
+}
+
+func (s *summary) print(w io.Writer) {
+	passed, failed, skipped := s.counts()
+
+	fmt.Fprintln(w)
+	for _, r := range s.results {
+		// Tests left out on the command line are only counted
+		// separately, so selecting a few tests doesn't list, or
+		// count as skipped, all the others.
+		if r.status == statusPass || r.reason == reasonNotSelected {
+			continue
+		}
+		fmt.Fprintf(w, "[%s] %s (%s)\n", r.status, r.label(), r.file)
+		if r.err != nil {
+			fmt.Fprintf(w, "\t%v\n", r.err)
+		}
+		if r.reason != "" {
+			fmt.Fprintf(w, "\t%s\n", r.reason)
+		}
+	}
+
+	switch s.stopped {
+	case nil:
+	case context.Canceled:
+		fmt.Fprintln(w, yellow("run interrupted"))
+	case context.DeadlineExceeded:
+		fmt.Fprintln(w, yellow("suite deadline exceeded"))
+	default:
+		fmt.Fprintln(w, yellow(fmt.Sprintf("run stopped: %v", s.stopped)))
+	}
+
+	fmt.Fprintf(w, "%d tests, %s passed, %s failed, %s skipped",
+		passed+failed+skipped,
+		green(passed),
+		red(failed),
+		yellow(skipped),
+	)
+	if n := s.notSelected(); n > 0 {
+		fmt.Fprintf(w, ", %d not selected", n)
+	}
+	fmt.Fprintf(w, " (%s)\n", s.duration.Round(time.Millisecond))
+}
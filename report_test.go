@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestSummaryCounts(t *testing.T) {
+	sum := &summary{}
+	sum.add(testResult{name: "a", status: statusPass})
+	sum.add(testResult{name: "b", status: statusFail, err: errors.New("boom")})
+	sum.add(testResult{name: "c", status: statusSkip})
+	sum.add(testResult{name: "d", status: statusPass})
+
+	passed, failed, skipped := sum.counts()
+	test.Equals(t, 2, passed)
+	test.Equals(t, 1, failed)
+	test.Equals(t, 1, skipped)
+	test.Assert(t, sum.failed())
+}
+
+func TestSummaryNoFailures(t *testing.T) {
+	sum := &summary{}
+	sum.add(testResult{name: "a", status: statusPass})
+	sum.add(testResult{name: "b", status: statusSkip})
+
+	test.Assert(t, !sum.failed())
+}
+
+func TestSummaryNotSelected(t *testing.T) {
+	sum := &summary{}
+	sum.add(testResult{name: "a", status: statusPass})
+	sum.add(testResult{name: "b", status: statusSkip, reason: "flaky"})
+	sum.add(testResult{name: "c", status: statusSkip, reason: reasonNotSelected})
+	sum.add(testResult{name: "d", status: statusSkip, reason: reasonNotSelected})
+
+	_, _, skipped := sum.counts()
+	test.Equals(t, 1, skipped)
+	test.Equals(t, 2, sum.notSelected())
+
+	var buf bytes.Buffer
+	sum.print(&buf)
+	out := buf.String()
+	test.Assert(t, !strings.Contains(out, "] c ("))
+	test.Assert(t, strings.Contains(out, "2 tests, "))
+	test.Assert(t, strings.Contains(out, " skipped, 2 not selected ("))
+}
+
+func TestSummaryPrintStopped(t *testing.T) {
+	sum := &summary{stopped: context.DeadlineExceeded}
+	sum.add(testResult{name: "a", status: statusSkip})
+
+	var buf bytes.Buffer
+	sum.print(&buf)
+	test.Assert(t, strings.Contains(buf.String(), "suite deadline exceeded"))
+}
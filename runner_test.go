@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/LUSHDigital/litmus/test"
+)
+
+// sessionServer sets a session cookie on /login and reports the
+// session cookie it receives on /whoami.
+func sessionServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+		case "/whoami":
+			w.Header().Set("Content-Type", "application/json")
+			session := ""
+			if c, err := r.Cookie("session"); err == nil {
+				session = c.Value
+			}
+			fmt.Fprintf(w, `{"session":%q}`, session)
+		}
+	}))
+}
+
+func TestRunnerCookieJar(t *testing.T) {
+	srv := sessionServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		scope string
+		clear bool
+		seed  map[string]string
+		exp   string
+	}{
+		{name: "suite jar keeps cookies", scope: jarScopeSuite, exp: "abc"},
+		{name: "no jar", scope: jarScopeNone, exp: ""},
+		{name: "cleared jar", scope: jarScopeSuite, clear: true, exp: ""},
+		{name: "seeded cookie", scope: jarScopeSuite, clear: true, seed: map[string]string{"session": "{{.token}}"}, exp: "seeded"},
+		{name: "seeded cookie without jar", scope: jarScopeNone, seed: map[string]string{"session": "{{.token}}"}, exp: "seeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &runner{
+				client:   &http.Client{},
+				env:      map[string]interface{}{"token": "seeded"},
+				jarScope: tt.scope,
+			}
+
+			login := &domain.RequestTest{Method: "GET", URL: srv.URL + "/login"}
+			test.ErrorNil(t, r.runRequest(context.Background(), ioutil.Discard, "", login))
+
+			whoami := &domain.RequestTest{
+				Method:       "GET",
+				URL:          srv.URL + "/whoami",
+				ClearCookies: tt.clear,
+				Cookies:      tt.seed,
+				Body:         map[string]interface{}{"session": tt.exp},
+			}
+			test.ErrorNil(t, r.runRequest(context.Background(), ioutil.Discard, "", whoami))
+		})
+	}
+}
+
+func TestRunnerSeededCookiePath(t *testing.T) {
+	srv := sessionServer()
+	defer srv.Close()
+
+	r := &runner{client: &http.Client{}, env: map[string]interface{}{}, jarScope: jarScopeSuite}
+	seed := &domain.RequestTest{
+		Method:  "GET",
+		URL:     srv.URL + "/api/v1/login",
+		Cookies: map[string]string{"session": "seeded"},
+	}
+	test.ErrorNil(t, r.runRequest(context.Background(), ioutil.Discard, "", seed))
+
+	whoami := &domain.RequestTest{
+		Method: "GET",
+		URL:    srv.URL + "/whoami",
+		Body:   map[string]interface{}{"session": "seeded"},
+	}
+	test.ErrorNil(t, r.runRequest(context.Background(), ioutil.Discard, "", whoami))
+}
+
+func TestRunnerUntil(t *testing.T) {
+	tests := []struct {
+		name  string
+		until *domain.RetryPolicy
+		err   string
+	}{
+		{name: "passes once ready", until: &domain.RetryPolicy{Attempts: 5, Delay: domain.Duration(time.Millisecond)}},
+		{name: "gives up", until: &domain.RetryPolicy{Attempts: 2, Delay: domain.Duration(time.Millisecond)}, err: "after 2 attempts: processing response"},
+		{name: "deadline", until: &domain.RetryPolicy{Delay: domain.Duration(time.Second), Deadline: domain.Duration(100 * time.Millisecond)}, err: "processing response"},
+		{name: "no policy", err: "processing response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					w.WriteHeader(http.StatusAccepted)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"status":"done"}`)
+			}))
+			defer srv.Close()
+
+			r := &runner{client: &http.Client{}, env: map[string]interface{}{}, jarScope: jarScopeNone}
+			err := r.runRequest(context.Background(), ioutil.Discard, "", &domain.RequestTest{
+				Method:    "GET",
+				URL:       srv.URL,
+				WantsCode: http.StatusOK,
+				Body:      map[string]interface{}{"status": "done"},
+				Until:     tt.until,
+			})
+			if tt.err == "" {
+				test.ErrorNil(t, err)
+				return
+			}
+			test.Assert(t, err != nil)
+			test.Assert(t, strings.HasPrefix(err.Error(), tt.err))
+		})
+	}
+}
+
+func TestRunnerRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	req := func(retry *domain.RetryPolicy) *domain.RequestTest {
+		return &domain.RequestTest{Method: "POST", URL: srv.URL, Payload: "x", WantsCode: http.StatusOK, Retry: retry}
+	}
+	r := &runner{client: &http.Client{}, env: map[string]interface{}{}, jarScope: jarScopeNone}
+
+	err := r.runRequest(context.Background(), ioutil.Discard, "", req(nil))
+	test.Assert(t, err != nil)
+	test.Assert(t, strings.HasPrefix(err.Error(), "performing request"))
+
+	err = r.runRequest(context.Background(), ioutil.Discard, "", req(&domain.RetryPolicy{Attempts: 3, Delay: domain.Duration(time.Millisecond)}))
+	test.ErrorNil(t, err)
+	test.Equals(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	r := &runner{client: &http.Client{}, env: map[string]interface{}{}, jarScope: jarScopeNone}
+	err := r.runRequest(context.Background(), ioutil.Discard, "", &domain.RequestTest{
+		Method:  "GET",
+		URL:     srv.URL,
+		Timeout: domain.Duration(20 * time.Millisecond),
+	})
+	test.Assert(t, err != nil)
+	test.Assert(t, strings.Contains(err.Error(), "context deadline exceeded"))
+
+	// A test's timeout can be longer than the client's.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	r.client = &http.Client{Timeout: 10 * time.Millisecond}
+	err = r.runRequest(context.Background(), ioutil.Discard, "", &domain.RequestTest{
+		Method:  "GET",
+		URL:     slow.URL,
+		Timeout: domain.Duration(time.Second),
+	})
+	test.ErrorNil(t, err)
+}
+
+func TestRunnerDelays(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	r := &runner{client: &http.Client{}, env: map[string]interface{}{}, jarScope: jarScopeNone}
+	start := time.Now()
+	err := r.runRequest(context.Background(), ioutil.Discard, "", &domain.RequestTest{
+		Method:      "GET",
+		URL:         srv.URL,
+		DelayBefore: domain.Duration(20 * time.Millisecond),
+		DelayAfter:  domain.Duration(20 * time.Millisecond),
+	})
+	test.ErrorNil(t, err)
+	test.Assert(t, time.Since(start) >= 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = r.runRequest(ctx, ioutil.Discard, "", &domain.RequestTest{
+		Method:      "GET",
+		URL:         srv.URL,
+		DelayBefore: domain.Duration(time.Second),
+	})
+	test.Assert(t, err != nil)
+	test.Equals(t, "waiting before request: context canceled", err.Error())
+}
+
+func TestRunRequestsStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "litmus")
+	test.ErrorNil(t, err)
+	defer os.RemoveAll(dir)
+
+	data := `
+[[litmus.test]]
+name="first"
+method="GET"
+url="http://localhost/first"
+[[litmus.test]]
+name="second"
+method="GET"
+url="http://localhost/second"`
+	test.ErrorNil(t, ioutil.WriteFile(filepath.Join(dir, "a_test.toml"), []byte(data), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, parallel := range []int{1, 2} {
+		r := &runner{
+			client:        &http.Client{},
+			env:           map[string]interface{}{},
+			jarScope:      jarScopeSuite,
+			parallel:      parallel,
+			parallelScope: parallelScopeTest,
+		}
+		sum, err := r.runRequests(ctx, dir, nil)
+		test.ErrorNil(t, err)
+		test.Equals(t, context.Canceled, sum.stopped)
+
+		_, _, skipped := sum.counts()
+		test.Equals(t, 2, skipped)
+	}
+}
+
+func TestNewRequestAuth(t *testing.T) {
+	req := &domain.RequestTest{
+		Method:  "GET",
+		URL:     "http://localhost",
+		Headers: map[string]string{"Content-Type": "application/json", "X-Trace": "1"},
+		Auth:    &domain.Auth{Bearer: "abc"},
+	}
+
+	request, err := newRequest(context.Background(), req, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, "Bearer abc", request.Header.Get("Authorization"))
+
+	req.UnsetHeaders = []string{"authorization", "X-Trace"}
+	request, err = newRequest(context.Background(), req, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, "", request.Header.Get("Authorization"))
+	test.Equals(t, "", request.Header.Get("X-Trace"))
+	test.Equals(t, "application/json", request.Header.Get("Content-Type"))
+
+	req.UnsetHeaders = nil
+	req.Headers = map[string]string{"Authorization": "Bearer own"}
+	request, err = newRequest(context.Background(), req, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, "Bearer own", request.Header.Get("Authorization"))
+}
+
+func TestRunnerVars(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%s}`, strings.TrimPrefix(r.URL.Path, "/items/"))
+	}))
+	defer srv.Close()
+
+	r := &runner{client: &http.Client{}, env: map[string]interface{}{"base": srv.URL}, jarScope: jarScopeNone}
+	err := r.runRequest(context.Background(), ioutil.Discard, "", &domain.RequestTest{
+		Method: "GET",
+		URL:    "{{.base}}/items/{{.id}}",
+		Body:   map[string]interface{}{"id": "{{.id}}"},
+		Vars:   map[string]interface{}{"id": int64(2)},
+	})
+	test.ErrorNil(t, err)
+
+	_, ok := r.env["id"]
+	test.Assert(t, !ok)
+}
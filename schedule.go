@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// plannedTest is a test selected to run, along with the indexes
+// of the tests that must complete before it can start.
+type plannedTest struct {
+	file string
+	test domain.RequestTest
+	deps []int
+
+	// phase is set for setup and teardown requests, and suite
+	// for those from the suite file.
+	phase string
+	suite bool
+
+	// before and after are the requests made around the test.
+	before []domain.RequestTest
+	after  []domain.RequestTest
+}
+
+// planTests selects the tests to run from files, in file order,
+// and adds the setup and teardown requests from the files and the
+// suite file, which may be nil. Tests that aren't run are returned
+// as skipped results, along with the reason they were skipped.
+func planTests(suite *domain.TestFile, files []domain.TestFile, sel *selector) (tests []*plannedTest, skipped []testResult, err error) {
+	known := make(map[string]bool)
+	only := false
+	for _, file := range files {
+		for _, test := range file.Litmus.Test {
+			known[test.Name] = true
+			known[test.BaseName()] = true
+			if test.Only && sel.selects(file.Path, &test) {
+				only = true
+			}
+		}
+	}
+
+	var all []*plannedTest
+	var reasons []string
+	for _, file := range files {
+		for _, test := range file.Litmus.Test {
+			reason := ""
+			switch {
+			case !sel.selects(file.Path, &test):
+				reason = reasonNotSelected
+			case test.Skip != "":
+				reason = test.Skip
+			case only && !test.Only:
+				reason = reasonNotOnly
+			}
+
+			all = append(all, &plannedTest{file: file.Path, test: test})
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if sel != nil && sel.withDeps {
+		includePrerequisites(all, reasons)
+	}
+
+	for i, t := range all {
+		if reasons[i] != "" {
+			skipped = append(skipped, testResult{name: t.test.Name, file: t.file, status: statusSkip, reason: reasons[i]})
+			continue
+		}
+		tests = append(tests, t)
+	}
+
+	for _, t := range tests {
+		for _, dep := range t.test.DependsOn {
+			if !known[dep] {
+				return nil, nil, errors.Errorf("test %q depends on unknown test %q", t.test.Name, dep)
+			}
+		}
+	}
+
+	tests = addFixtures(suite, files, tests)
+	if err = resolveHooks(suite, files, tests); err != nil {
+		return nil, nil, err
+	}
+	linkFixtures(tests)
+	return tests, skipped, nil
+}
+
+// includePrerequisites clears the reasons for skipping the tests
+// that the tests being run depend on, directly or indirectly, so
+// they're run too. Tests marked skip stay skipped.
+func includePrerequisites(tests []*plannedTest, reasons []string) {
+	requests := make([]*domain.RequestTest, len(tests))
+	for i, t := range tests {
+		requests[i] = &t.test
+	}
+	deps := testDependencies(requests)
+
+	var queue []int
+	for i, reason := range reasons {
+		if reason == "" {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for d := range deps[i] {
+			if reasons[d] == reasonNotSelected || reasons[d] == reasonNotOnly {
+				reasons[d] = ""
+				queue = append(queue, d)
+			}
+		}
+	}
+}
+
+// linkDependencies works out which tests each test has to wait
+// for. As well as its setup requests, a test waits for the tests
+// named in its depends_on, for the latest earlier test that sets
+// each environment value it uses and, in file scope, for the test
+// before it in its file, in the order the tests are run serially.
+func linkDependencies(tests []*plannedTest, scope string) error {
+	requests := make([]*domain.RequestTest, len(tests))
+	for i, t := range tests {
+		requests[i] = &t.test
+	}
+
+	for i, deps := range testDependencies(requests) {
+		t := tests[i]
+		for _, d := range t.deps {
+			deps[d] = true
+		}
+
+		t.deps = t.deps[:0]
+		for d := range deps {
+			t.deps = append(t.deps, d)
+		}
+		sort.Ints(t.deps)
+	}
+	if err := checkCycles(tests); err != nil {
+		return err
+	}
+
+	// Following the serial order, rather than the declared one,
+	// means a test can depend on one declared after it in its
+	// file without making a cycle.
+	if scope == parallelScopeFile {
+		last := make(map[string]int)
+		for _, i := range serialOrder(tests) {
+			t := tests[i]
+			if p, ok := last[t.file]; ok && !hasDep(t, p) {
+				t.deps = append(t.deps, p)
+				sort.Ints(t.deps)
+			}
+			last[t.file] = i
+		}
+	}
+	return nil
+}
+
+func hasDep(t *plannedTest, d int) bool {
+	for _, dep := range t.deps {
+		if dep == d {
+			return true
+		}
+	}
+	return false
+}
+
+// testDependencies returns, for each test, the indexes of the
+// tests it depends on: those named in its depends_on, by their own
+// or their base name, and the latest earlier test that sets each
+// environment value it uses.
+func testDependencies(tests []*domain.RequestTest) []map[int]bool {
+	// A data-driven test's base name stands for all of the tests
+	// generated from it.
+	byName := make(map[string][]int)
+	for i, t := range tests {
+		byName[t.Name] = append(byName[t.Name], i)
+		if base := t.BaseName(); base != t.Name {
+			byName[base] = append(byName[base], i)
+		}
+	}
+
+	producers := make(map[string]int)
+	all := make([]map[int]bool, len(tests))
+	for i, t := range tests {
+		deps := make(map[int]bool)
+		for _, ref := range t.EnvRefs() {
+			if p, ok := producers[ref]; ok {
+				deps[p] = true
+			}
+		}
+		for _, name := range t.DependsOn {
+			for _, j := range byName[name] {
+				if j != i {
+					deps[j] = true
+				}
+			}
+		}
+		for _, key := range t.SetKeys() {
+			producers[key] = i
+		}
+		all[i] = deps
+	}
+	return all
+}
+
+// checkCycles returns an error naming a test that can never start
+// because its dependencies lead back to itself.
+func checkCycles(tests []*plannedTest) error {
+	remaining, dependents := dependencyGraph(tests)
+
+	var queue []int
+	for i := range tests {
+		if remaining[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[i] {
+			if remaining[d]--; remaining[d] == 0 {
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	for i, t := range tests {
+		if remaining[i] > 0 {
+			return errors.Errorf("test %q is part of a dependency cycle", t.test.Name)
+		}
+	}
+	return nil
+}
+
+// serialOrder returns the indexes of tests in the order they're run
+// one at a time: the order they were planned in, except that a test
+// is moved after the tests it depends on. The tests mustn't have a
+// dependency cycle.
+func serialOrder(tests []*plannedTest) []int {
+	remaining, dependents := dependencyGraph(tests)
+
+	var ready []int
+	for i := range tests {
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	order := make([]int, 0, len(tests))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		for _, d := range dependents[i] {
+			if remaining[d]--; remaining[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+		sort.Ints(ready)
+	}
+	return order
+}
+
+// dependencyGraph returns the number of dependencies each test is
+// waiting for, and the tests that depend on each test.
+func dependencyGraph(tests []*plannedTest) (remaining []int, dependents [][]int) {
+	remaining = make([]int, len(tests))
+	dependents = make([][]int, len(tests))
+	for i, t := range tests {
+		remaining[i] = len(t.deps)
+		for _, d := range t.deps {
+			dependents[d] = append(dependents[d], i)
+		}
+	}
+	return
+}
+
+// runParallel runs tests on r.parallel workers, starting each test
+// once its dependencies are complete. Each test's output is written
+// in one piece when it finishes, and results are added to sum in
+// the order the tests were planned.
+func (r *runner) runParallel(ctx context.Context, tests []*plannedTest, sum *summary) {
+	results := make([]testResult, len(tests))
+	remaining, dependents := dependencyGraph(tests)
+
+	ready := make(chan int, len(tests))
+	done := make(chan int)
+	for i := range tests {
+		if remaining[i] == 0 {
+			ready <- i
+		}
+	}
+
+	var stop int32
+	var out sync.Mutex
+	for w := 0; w < r.parallel; w++ {
+		go func() {
+			for i := range ready {
+				buf := &bytes.Buffer{}
+				results[i] = r.runPlanned(ctx, buf, tests, results, i, atomic.LoadInt32(&stop) == 1)
+
+				out.Lock()
+				buf.WriteTo(os.Stdout)
+				out.Unlock()
+				done <- i
+			}
+		}()
+	}
+
+	for n := 0; n < len(tests); n++ {
+		i := <-done
+		if results[i].status == statusFail && r.failFast && tests[i].phase != phaseTeardown {
+			atomic.StoreInt32(&stop, 1)
+		}
+		for _, d := range dependents[i] {
+			if remaining[d]--; remaining[d] == 0 {
+				ready <- d
+			}
+		}
+	}
+	close(ready)
+
+	for _, result := range results {
+		sum.add(result)
+	}
+}
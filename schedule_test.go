@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestLinkDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		tests []*plannedTest
+		exp   [][]int
+		err   string
+	}{
+		{
+			name:  "file scope chains tests in a file",
+			scope: parallelScopeFile,
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "a1"}},
+				{file: "a", test: domain.RequestTest{Name: "a2"}},
+				{file: "b", test: domain.RequestTest{Name: "b1"}},
+			},
+			exp: [][]int{nil, {0}, nil},
+		},
+		{
+			name:  "test scope runs independent tests together",
+			scope: parallelScopeTest,
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "a1"}},
+				{file: "a", test: domain.RequestTest{Name: "a2"}},
+			},
+			exp: [][]int{nil, nil},
+		},
+		{
+			name:  "consumer waits for producer",
+			scope: parallelScopeTest,
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "login", Body: map[string]interface{}{"token": map[string]interface{}{"token": ""}}}},
+				{file: "b", test: domain.RequestTest{Name: "other"}},
+				{file: "b", test: domain.RequestTest{Name: "me", Headers: map[string]string{"Authorization": "{{.token}}"}}},
+			},
+			exp: [][]int{nil, nil, {0}},
+		},
+		{
+			name:  "depends on",
+			scope: parallelScopeTest,
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "create"}},
+				{file: "b", test: domain.RequestTest{Name: "list", DependsOn: []string{"create"}}},
+			},
+			exp: [][]int{nil, {0}},
+		},
+		{
+			name:  "cycle",
+			scope: parallelScopeTest,
+			tests: []*plannedTest{
+				{file: "a", test: domain.RequestTest{Name: "x", DependsOn: []string{"y"}}},
+				{file: "a", test: domain.RequestTest{Name: "y", DependsOn: []string{"x"}}},
+			},
+			err: `test "x" is part of a dependency cycle`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := linkDependencies(tt.tests, tt.scope)
+			if tt.err != "" {
+				test.Assert(t, err != nil)
+				test.Equals(t, tt.err, err.Error())
+				return
+			}
+			test.ErrorNil(t, err)
+			for i, pt := range tt.tests {
+				if len(tt.exp[i]) == 0 {
+					test.Equals(t, 0, len(pt.deps))
+					continue
+				}
+				test.Equals(t, tt.exp[i], pt.deps)
+			}
+		})
+	}
+}
+
+func TestPlanTestsUnknownDependency(t *testing.T) {
+	files := []domain.TestFile{{Path: "a"}}
+	files[0].Litmus.Test = []domain.RequestTest{{Name: "x", DependsOn: []string{"missing"}}}
+
+	_, _, err := planTests(nil, files, nil)
+	test.Assert(t, err != nil)
+	test.Equals(t, `test "x" depends on unknown test "missing"`, err.Error())
+}
+
+func TestRunParallel(t *testing.T) {
+	var running, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	tests := []*plannedTest{
+		{file: "a", test: domain.RequestTest{
+			Name: "produce", Method: "GET", URL: srv.URL + "/first",
+			Body: map[string]interface{}{"first": map[string]interface{}{"path": "/first"}},
+		}},
+		{file: "b", test: domain.RequestTest{Name: "b", Method: "GET", URL: srv.URL + "/b"}},
+		{file: "c", test: domain.RequestTest{Name: "c", Method: "GET", URL: srv.URL + "/c"}},
+		{file: "d", test: domain.RequestTest{
+			Name: "consume", Method: "GET", URL: srv.URL + "{{.first}}",
+			Body: map[string]interface{}{"path": "/first"},
+		}},
+	}
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{},
+		jarScope:      jarScopeSuite,
+		parallel:      4,
+		parallelScope: parallelScopeTest,
+	}
+	test.ErrorNil(t, linkDependencies(tests, r.parallelScope))
+
+	sum := &summary{}
+	r.runParallel(context.Background(), tests, sum)
+
+	test.Equals(t, 4, len(sum.results))
+	for i, result := range sum.results {
+		test.Equals(t, tests[i].test.Name, result.name)
+		test.Equals(t, statusPass, result.status)
+	}
+	test.Equals(t, "/first", r.env["first"])
+	test.Assert(t, atomic.LoadInt32(&peak) > 1)
+}
+
+func TestPlanTestsWithDeps(t *testing.T) {
+	files := []domain.TestFile{{Path: "a"}, {Path: "b"}}
+	files[0].Litmus.Test = []domain.RequestTest{
+		{Name: "login", Body: map[string]interface{}{"token": map[string]interface{}{"token": "abc"}}},
+		{Name: "create order", Headers: map[string]string{"Authorization": "{{.token}}"}, Body: map[string]interface{}{"order_id": map[string]interface{}{"id": "1"}}},
+		{Name: "unrelated"},
+	}
+	files[1].Litmus.Test = []domain.RequestTest{
+		{Name: "seed"},
+		{Name: "update order", URL: "/orders/{{.order_id}}", DependsOn: []string{"seed"}},
+	}
+
+	for _, withDeps := range []bool{false, true} {
+		sel, err := newSelector("", "update order", nil, nil, nil, "")
+		test.ErrorNil(t, err)
+		sel.withDeps = withDeps
+
+		tests, _, err := planTests(nil, files, sel)
+		test.ErrorNil(t, err)
+
+		var names []string
+		for _, pt := range tests {
+			names = append(names, pt.test.Name)
+		}
+		if withDeps {
+			test.Equals(t, []string{"login", "create order", "seed", "update order"}, names)
+		} else {
+			test.Equals(t, []string{"update order"}, names)
+		}
+	}
+}
+
+func TestRunRequestsSerialDependencyOrder(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="list"
+url="{{.base}}/list"
+depends_on=["create"]
+[[litmus.test]]
+name="create"
+method="POST"
+url="{{.base}}/create"
+[[litmus.test]]
+name="unrelated"
+url="{{.base}}/unrelated"`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeNone,
+		parallel:      1,
+		parallelScope: parallelScopeFile,
+	}
+	_, err := r.runRequests(context.Background(), dir, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, []string{"/create", "/list", "/unrelated"}, paths())
+}
+
+func TestRunRequestsWithDepsOrder(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="list"
+url="{{.base}}/list"
+depends_on=["create"]
+[[litmus.test]]
+name="create"
+method="POST"
+url="{{.base}}/create"
+[[litmus.test]]
+name="unrelated"
+url="{{.base}}/unrelated"`,
+	})
+	defer os.RemoveAll(dir)
+
+	sel, err := newSelector(dir, "list", nil, nil, nil, "")
+	test.ErrorNil(t, err)
+	sel.withDeps = true
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeNone,
+		parallel:      1,
+		parallelScope: parallelScopeFile,
+	}
+	_, err = r.runRequests(context.Background(), dir, sel)
+	test.ErrorNil(t, err)
+	test.Equals(t, []string{"/create", "/list"}, paths())
+}
+
+func TestRunRequestsDependsOnDataDriven(t *testing.T) {
+	srv, paths := recordingServer()
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"a_test.toml": `
+[[litmus.test]]
+name="list"
+url="{{.base}}/list"
+depends_on=["create"]
+[[litmus.test]]
+name="create"
+method="POST"
+url="{{.base}}/create/{{.sku}}"
+[litmus.test.each]
+rows=[{sku="a"}, {sku="b"}]`,
+	})
+	defer os.RemoveAll(dir)
+
+	r := &runner{
+		client:        &http.Client{},
+		env:           map[string]interface{}{"base": srv.URL},
+		jarScope:      jarScopeNone,
+		parallel:      1,
+		parallelScope: parallelScopeTest,
+	}
+	_, err := r.runRequests(context.Background(), dir, nil)
+	test.ErrorNil(t, err)
+	test.Equals(t, []string{"/create/a", "/create/b", "/list"}, paths())
+}
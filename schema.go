@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of file a schema can be generated for.
+const (
+	schemaTest = "test"
+	schemaEnv  = "env"
+)
+
+// schemaCommand returns the command that prints the JSON Schema of
+// test or env files.
+func schemaCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "schema [test|env]",
+		Short:     schemaShort,
+		Long:      schemaLong,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{schemaTest, schemaEnv},
+		Run: func(cmd *cobra.Command, args []string) {
+			kind := schemaTest
+			if len(args) > 0 {
+				kind = args[0]
+			}
+			if err := writeSchema(os.Stdout, kind); err != nil {
+				log.Print(err)
+				os.Exit(exitError)
+			}
+		},
+	}
+}
+
+// writeSchema writes the schema for the kind of file as indented
+// JSON.
+func writeSchema(w io.Writer, kind string) error {
+	var schema map[string]interface{}
+	switch kind {
+	case schemaTest:
+		schema = domain.TestFileSchema()
+	case schemaEnv:
+		schema = domain.EnvSchema()
+	default:
+		return errors.Errorf("unknown schema %q, expected test or env", kind)
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling schema")
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
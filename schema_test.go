@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/LUSHDigital/litmus/test"
+)
+
+// TestSchemaFiles checks the published schemas are up to date. Run
+// make schema to regenerate them.
+func TestSchemaFiles(t *testing.T) {
+	for _, kind := range []string{schemaTest, schemaEnv} {
+		t.Run(kind, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			test.ErrorNil(t, writeSchema(buf, kind))
+
+			published, err := ioutil.ReadFile(filepath.Join("schema", kind+".schema.json"))
+			test.ErrorNil(t, err)
+			if !bytes.Equal(published, buf.Bytes()) {
+				t.Errorf("schema/%s.schema.json is out of date; run make schema", kind)
+			}
+		})
+	}
+
+	test.Assert(t, writeSchema(&bytes.Buffer{}, "suite") != nil)
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/pkg/errors"
+)
+
+// Reasons given for tests that are skipped before they're run.
+const (
+	reasonNotSelected = "not selected"
+	reasonNotOnly     = "another test is marked only"
+)
+
+// selector decides which of the loaded tests are run.
+type selector struct {
+	// root is the configuration folder, which file patterns are
+	// relative to.
+	root string
+
+	// name, if set, must match a test's name exactly.
+	name string
+
+	run     []*regexp.Regexp
+	exclude []*regexp.Regexp
+	files   []string
+	tags    tagExpr
+
+	// withDeps also runs the tests that selected tests depend on.
+	withDeps bool
+}
+
+// newSelector builds a selector from the command line flags.
+func newSelector(root, name string, run, exclude, files []string, tags string) (s *selector, err error) {
+	s = &selector{root: root, name: name, files: files}
+	if s.run, err = compilePatterns(run); err != nil {
+		return nil, errors.Wrap(err, "--run")
+	}
+	if s.exclude, err = compilePatterns(exclude); err != nil {
+		return nil, errors.Wrap(err, "--exclude")
+	}
+	for _, f := range files {
+		if _, err = filepath.Match(f, ""); err != nil {
+			return nil, errors.Wrapf(err, "--file %q", f)
+		}
+	}
+	if tags != "" {
+		if s.tags, err = parseTagExpr(tags); err != nil {
+			return nil, errors.Wrap(err, "--tags")
+		}
+	}
+	return s, nil
+}
+
+// selects reports whether the test, loaded from file, was chosen
+// on the command line.
+func (s *selector) selects(file string, t *domain.RequestTest) bool {
+	if s == nil {
+		return true
+	}
+	if s.name != "" && t.Name != s.name {
+		return false
+	}
+	if len(s.run) > 0 && !matchAny(s.run, t.Name) {
+		return false
+	}
+	if matchAny(s.exclude, t.Name) {
+		return false
+	}
+	if len(s.files) > 0 && !s.matchFile(file) {
+		return false
+	}
+	if s.tags != nil {
+		tags := make(map[string]bool, len(t.Tags))
+		for _, tag := range t.Tags {
+			tags[tag] = true
+		}
+		if !s.tags(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchFile reports whether file matches any of the file patterns,
+// either by its path relative to the configuration folder or by
+// its base name.
+func (s *selector) matchFile(file string) bool {
+	rel, err := filepath.Rel(s.root, file)
+	if err != nil {
+		rel = file
+	}
+	for _, pattern := range s.files {
+		for _, candidate := range []string{rel, filepath.Base(file)} {
+			if ok, _ := filepath.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, p := range patterns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles test name patterns. A pattern enclosed
+// in slashes, such as /^create/, is a regular expression; any other
+// pattern is a glob matching the whole name, in which * matches any
+// run of characters and ? matches a single one.
+func compilePatterns(patterns []string) (compiled []*regexp.Regexp, err error) {
+	for _, p := range patterns {
+		expr := globToRegexp(p)
+		if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			expr = p[1 : len(p)-1]
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", p)
+		}
+		compiled = append(compiled, re)
+	}
+	return
+}
+
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// tagExpr reports whether a set of tags satisfies a tag expression.
+type tagExpr func(tags map[string]bool) bool
+
+// parseTagExpr parses a boolean expression over tag names, such as
+// "smoke && !slow" or "(users || orders) && !flaky". ! binds more
+// tightly than &&, which binds more tightly than ||.
+func parseTagExpr(s string) (tagExpr, error) {
+	p := &tagParser{tokens: tokenizeTags(s)}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, errors.Errorf("unexpected %q in tag expression %q", tok, s)
+	}
+	return expr, nil
+}
+
+func tokenizeTags(s string) (tokens []string) {
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '!':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		default:
+			j := i
+			for j < len(s) && isTagChar(rune(s[j])) {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return
+}
+
+func isTagChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.:/", r)
+}
+
+type tagParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *tagParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *tagParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *tagParser) or() (tagExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(tags map[string]bool) bool { return l(tags) || right(tags) }
+	}
+	return left, nil
+}
+
+func (p *tagParser) and() (tagExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(tags map[string]bool) bool { return l(tags) && right(tags) }
+	}
+	return left, nil
+}
+
+func (p *tagParser) unary() (tagExpr, error) {
+	switch tok := p.next(); {
+	case tok == "":
+		return nil, errors.New("unexpected end of tag expression")
+	case tok == "!":
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(tags map[string]bool) bool { return !expr(tags) }, nil
+	case tok == "(":
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("missing ) in tag expression")
+		}
+		return expr, nil
+	case isTagChar(rune(tok[0])):
+		return func(tags map[string]bool) bool { return tags[tok] }, nil
+	default:
+		return nil, errors.Errorf("unexpected %q in tag expression", tok)
+	}
+}
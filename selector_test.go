@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/LUSHDigital/litmus/domain"
+	"github.com/LUSHDigital/litmus/test"
+)
+
+func TestParseTagExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		tags []string
+		exp  bool
+	}{
+		{expr: "smoke", tags: []string{"smoke"}, exp: true},
+		{expr: "smoke", tags: []string{"slow"}, exp: false},
+		{expr: "smoke && !slow", tags: []string{"smoke"}, exp: true},
+		{expr: "smoke && !slow", tags: []string{"smoke", "slow"}, exp: false},
+		{expr: "users || orders", tags: []string{"orders"}, exp: true},
+		{expr: "users || orders && slow", tags: []string{"users"}, exp: true},
+		{expr: "(users || orders) && slow", tags: []string{"users"}, exp: false},
+		{expr: "!!team:payments", tags: []string{"team:payments"}, exp: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parseTagExpr(tt.expr)
+			test.ErrorNil(t, err)
+
+			tags := map[string]bool{}
+			for _, tag := range tt.tags {
+				tags[tag] = true
+			}
+			test.Equals(t, tt.exp, expr(tags))
+		})
+	}
+}
+
+func TestParseTagExprErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{expr: "smoke &&", err: "unexpected end of tag expression"},
+		{expr: "(smoke", err: "missing ) in tag expression"},
+		{expr: "smoke slow", err: `unexpected "slow" in tag expression "smoke slow"`},
+		{expr: "smoke & slow", err: `unexpected "&" in tag expression "smoke & slow"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseTagExpr(tt.expr)
+			test.Assert(t, err != nil)
+			test.Equals(t, tt.err, err.Error())
+		})
+	}
+}
+
+func TestSelectorSelects(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     []string
+		exclude []string
+		files   []string
+		tags    string
+		file    string
+		test    domain.RequestTest
+		exp     bool
+	}{
+		{name: "everything", file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "glob", run: []string{"get *"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "glob is anchored", run: []string{"user"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: false},
+		{name: "regexp", run: []string{"/user$/"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "any run pattern", run: []string{"create *", "get *"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "exclude", exclude: []string{"*user"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: false},
+		{name: "file by base name", files: []string{"a_*"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "file by relative path", files: []string{"users/*"}, file: "tests/users/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: true},
+		{name: "other file", files: []string{"b_*"}, file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: false},
+		{name: "tags", tags: "smoke && !slow", file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user", Tags: []string{"smoke"}}, exp: true},
+		{name: "untagged", tags: "smoke", file: "tests/a_test.toml", test: domain.RequestTest{Name: "get user"}, exp: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, err := newSelector("tests", "", tt.run, tt.exclude, tt.files, tt.tags)
+			test.ErrorNil(t, err)
+			test.Equals(t, tt.exp, sel.selects(tt.file, &tt.test))
+		})
+	}
+}
+
+func TestPlanTestsMarkers(t *testing.T) {
+	files := []domain.TestFile{{Path: "a"}}
+	files[0].Litmus.Test = []domain.RequestTest{
+		{Name: "focused", Only: true},
+		{Name: "other"},
+		{Name: "broken", Only: true, Skip: "waiting on a fix"},
+		{Name: "excluded", Only: true},
+	}
+	sel, err := newSelector("", "", nil, []string{"excluded"}, nil, "")
+	test.ErrorNil(t, err)
+
+	tests, skipped, err := planTests(nil, files, sel)
+	test.ErrorNil(t, err)
+
+	test.Equals(t, 1, len(tests))
+	test.Equals(t, "focused", tests[0].test.Name)
+
+	reasons := map[string]string{}
+	for _, r := range skipped {
+		test.Equals(t, statusSkip, r.status)
+		reasons[r.name] = r.reason
+	}
+	test.Equals(t, map[string]string{
+		"other":    reasonNotOnly,
+		"broken":   "waiting on a fix",
+		"excluded": reasonNotSelected,
+	}, reasons)
+}
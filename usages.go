@@ -17,5 +17,40 @@ for you to write endpoint tests with`
 	eFlagUsage = `environment variables: example baseurl=httpbin.org"`
 	nFlagUsage = `name of specific test to run`
 	tFlagUsage = `override timeout duration, value provided in seconds (default 5 seconds)`
-	uFlagUsage = `name of the specific env file to use`
+	uFlagUsage = `env file to use instead of the config folder's env.toml; may be repeated, with later files taking precedence`
+
+	failFastFlagUsage  = `stop running tests after the first failure`
+	vFlagUsage         = `print the request and response of every test, not just failures`
+	bodyLimitFlagUsage = `maximum number of body bytes to display, 0 for no limit`
+	cookieJarFlagUsage = `scope of the cookie jar: suite, file or none`
+	parallelFlagUsage  = `number of tests to run concurrently`
+	deadlineFlagUsage  = `time limit for the whole run, such as 5m; tests not started by then are skipped`
+	runFlagUsage       = `run tests whose name matches a glob, or a regular expression between slashes such as /^create/; may be repeated`
+	excludeFlagUsage   = `skip tests whose name matches a glob or /regular expression/; may be repeated`
+	fileFlagUsage      = `run tests from files matching a glob, by name or path relative to the config folder; may be repeated`
+	tagsFlagUsage      = `run tests whose tags satisfy an expression, such as 'smoke && !slow'`
+	withDepsFlagUsage  = `also run the tests that selected tests depend on, such as those capturing values they use`
+
+	laxTemplatesFlagUsage = `allow templates to use undefined variables, which render as "<no value>"`
+	includeFlagUsage      = `only load test files whose path, relative to the config folder, matches a glob such as 'orders/**'; may be repeated`
+	ignoreFlagUsage       = `don't load test files whose path, relative to the config folder, matches a glob such as 'fixtures'; may be repeated`
+	strictFlagUsage       = `reject test files with unknown keys or invalid requests before running anything`
+
+	lintShort = "Check test files for problems without running them."
+	lintLong  = `Lint decodes every test file strictly, rejecting unknown keys and values
+of the wrong type, then checks each request's method, URL, templates and
+assertions, test names, dependencies and hooks, and the variables templates use.`
+
+	explainFlagUsage = `show the layer each value came from`
+
+	envShort = "Print the environment tests are run with."
+	envLong  = `Env prints the values templates can use, built from the env files, the .env
+file and LITMUS_ variables of the OS environment, and the -e flags, each
+taking precedence over the last.`
+
+	schemaShort = "Print the JSON Schema of test or env files."
+	schemaLong  = `Schema prints a JSON Schema describing test files, including suite files,
+or env files, for editors to validate and complete them with.`
+
+	parallelScopeFlagUsage = `unit of parallelism: file runs the tests in each file in order, test runs any independent test concurrently`
 )